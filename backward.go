@@ -0,0 +1,203 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// backwardBlockSize is how much is read at a time when reading backwards
+const backwardBlockSize = 64 * 1024
+
+// tailWindowLines is how many lines are shown when jumping to the end
+const tailWindowLines = 1000
+
+// Message with the exact number of the line starting at offset, once the index can tell
+type lineNumberMsg struct {
+	offset     int64
+	lineNumber int
+	err        error
+}
+
+// findLinesBefore returns where the n lines ending at end start, or 0 when
+// there are fewer than n lines before end
+func findLinesBefore(r io.ReaderAt, end int64, n int) (int64, error) {
+	buf := make([]byte, backwardBlockSize)
+	count := 0
+	pos := end
+
+	for pos > 0 {
+		size := int64(len(buf))
+		if size > pos {
+			size = pos
+		}
+		pos -= size
+		if _, err := r.ReadAt(buf[:size], pos); err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == end-1 {
+				continue // The newline ending the last line doesn't start one
+			}
+			count++
+			if count == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// loadTailWindow reads the last lines of a file, numbering them from an
+// estimate unless the window reaches back to the start of the file. It
+// returns the file where reading stopped so tailing can continue from there,
+// which the caller closes.
+func loadTailWindow(filename string, n int) (lines []LogLine, approx bool, tail *logFile, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, false, nil, err
+	}
+	start, err := findLinesBefore(file, stat.Size(), n)
+	file.Close()
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	logFile, err := openLogFileAt(filename, start)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	lines, _, err = logFile.readLines(1, 0)
+	if err != nil {
+		logFile.Close()
+		return nil, false, nil, err
+	}
+	end := logFile.offset()
+
+	// Estimate how many lines come before the window from the average
+	// length of the lines in it
+	if start > 0 && len(lines) > 0 {
+		before := int(float64(start) * float64(len(lines)) / float64(end-start))
+		if before < 1 {
+			before = 1 // There's at least one line before a window that doesn't start the file
+		}
+		shiftLineNumbers(lines, before)
+		approx = true
+	}
+	return lines, approx, logFile, nil
+}
+
+// tailWindowCmd loads the last lines of a file in the background
+func tailWindowCmd(filename string) tea.Cmd {
+	return func() tea.Msg {
+		lines, approx, file, err := loadTailWindow(filename, tailWindowLines)
+		return jumpMsg{newLines: lines, file: file, atEOF: true, target: lastLine, approx: approx, err: err}
+	}
+}
+
+// loadPrevLinesCmd reads the lines just before first by reading backwards
+// from where it starts, numbering them so they end right before it
+func loadPrevLinesCmd(filename string, first LogLine) tea.Cmd {
+	return func() tea.Msg {
+		file, err := os.Open(filename)
+		if err != nil {
+			return prevLinesMsg{err: err}
+		}
+		start, err := findLinesBefore(file, first.Offset, jumpChunkSize)
+		file.Close()
+		if err != nil {
+			return prevLinesMsg{err: err}
+		}
+
+		logFile, err := openLogFileAt(filename, start)
+		if err != nil {
+			return prevLinesMsg{err: err}
+		}
+		defer logFile.Close()
+
+		var lines []LogLine
+		for logFile.offset() < first.Offset {
+			newLines, _, err := logFile.readLines(len(lines)+1, 1)
+			if err != nil {
+				return prevLinesMsg{err: err}
+			}
+			if len(newLines) == 0 {
+				break
+			}
+			lines = append(lines, newLines...)
+		}
+		shiftLineNumbers(lines, first.LineNumber-1-len(lines))
+		return prevLinesMsg{newLines: lines, before: first.Offset, fromStart: start == 0}
+	}
+}
+
+// lineNumberAtCmd counts the lines before offset using the index, reading at
+// most one indexed block
+func lineNumberAtCmd(filename string, index *lineIndex, offset int64) tea.Cmd {
+	return func() tea.Msg {
+		k := sort.Search(len(index.offsets), func(k int) bool {
+			return index.offsets[k] > offset
+		}) - 1
+
+		file, err := openLogFileAt(filename, index.offsets[k])
+		if err != nil {
+			return lineNumberMsg{offset: offset, err: err}
+		}
+		defer file.Close()
+
+		lineNumber := k*index.interval + 1
+		for file.offset() < offset {
+			before := file.offset()
+			if err := file.skipLines(1); err != nil {
+				return lineNumberMsg{offset: offset, err: err}
+			}
+			if file.offset() == before {
+				// The file is shorter than it was, the line is gone
+				return lineNumberMsg{offset: offset, err: io.ErrUnexpectedEOF}
+			}
+			lineNumber++
+		}
+		return lineNumberMsg{offset: offset, lineNumber: lineNumber}
+	}
+}
+
+// shiftLineNumbers adds delta to the number of every line
+func shiftLineNumbers(lines []LogLine, delta int) {
+	for i := range lines {
+		lines[i].LineNumber += delta
+	}
+}
+
+// renumberLines shifts every line in memory so the first one is numbered
+// firstLineNumber, once the estimate can be replaced with the exact number
+func (m *Model) renumberLines(firstLineNumber int) {
+	if len(m.lines) == 0 {
+		return
+	}
+	delta := firstLineNumber - m.lines[0].LineNumber
+	if m.linesShared() {
+		// Don't change the lines under a background job. A filter job
+		// filters them all again when it sees they were renumbered.
+		m.lines = append([]LogLine(nil), m.lines...)
+	}
+	shiftLineNumbers(m.lines, delta)
+	if len(m.filters) > 0 {
+		shiftLineNumbers(m.filteredLines, delta)
+	}
+	m.lastLineNum += delta
+	m.approxLineNumbers = false
+}
+
+// canSeek reports whether the file can be read from any offset
+func (m Model) canSeek() bool {
+	return m.compression == compressionNone && len(m.sources) == 0 && m.stream == nil && !m.fileReplaced
+}
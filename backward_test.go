@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestFindLinesBefore tests finding where the last lines of some content start
+func TestFindLinesBefore(t *testing.T) {
+	// Large enough to span several blocks
+	content := generateLogContent(5000)
+	lineStart := func(lineNumber int) int64 {
+		return int64(strings.Index(content, fmt.Sprintf(`{"line": %d,`, lineNumber)))
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		end     int64
+		n       int
+		want    int64
+	}{
+		{"last lines", content, int64(len(content)), 10, lineStart(4991)},
+		{"across blocks", content, int64(len(content)), 3000, lineStart(2001)},
+		{"before a line", content, lineStart(101), 100, 0},
+		{"fewer lines than asked", content, lineStart(50), 100, 0},
+		{"unterminated last line", "a\nb\nc", 5, 2, 2},
+		{"empty", "", 0, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findLinesBefore(strings.NewReader(tt.content), tt.end, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected offset %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadTailWindow tests reading the end of a file with estimated line numbers
+func TestLoadTailWindow(t *testing.T) {
+	path := writeTestFile(t, "big.log", []byte(generateLogContent(5000)))
+
+	lines, approx, tail, err := loadTailWindow(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail.Close()
+	if len(lines) != 100 || !approx {
+		t.Fatalf("Expected 100 lines with estimated numbers, got %d (approx %v)", len(lines), approx)
+	}
+	if !strings.Contains(lines[99].RawLine, `"line": 5000,`) {
+		t.Errorf("Expected the last line of the file, got %q", lines[99].RawLine)
+	}
+	if n := lines[99].LineNumber; n < 4500 || n > 5500 {
+		t.Errorf("Expected an estimate close to 5000, got %d", n)
+	}
+	if content := generateLogContent(5000); tail.offset() != int64(len(content)) {
+		t.Errorf("Expected to stop at the end of the file, got %d", tail.offset())
+	}
+
+	// A window that reaches the start of the file is numbered exactly
+	small := writeTestFile(t, "small.log", []byte(generateLogContent(20)))
+	lines, approx, tail, err = loadTailWindow(small, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail.Close()
+	if approx || len(lines) != 20 || lines[0].LineNumber != 1 {
+		t.Errorf("Expected exact lines 1 to 20, got %d lines from %d (approx %v)", len(lines), lines[0].LineNumber, approx)
+	}
+}
+
+// TestEndWhileIndexing tests jumping to the end before the index is ready
+func TestEndWhileIndexing(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+	idx := model.index
+	model.index = nil
+	model.indexing = true
+
+	model = runCmd(model, model.goToLine(lastLine))
+
+	if !model.approxLineNumbers || !model.hasLinesAbove() {
+		t.Fatal("Expected the end to be read backwards with estimated line numbers")
+	}
+	last := model.getVisibleLines()[model.cursor]
+	if !strings.Contains(last.RawLine, `"line": 5000,`) {
+		t.Errorf("Expected the cursor on the last line of the file, got %q", last.RawLine)
+	}
+	model.width = 300 // Room for the temporary file's long path
+	if !strings.Contains(model.View(), "Line ~") {
+		t.Error("Expected estimated line numbers to be marked in the status bar")
+	}
+
+	// Once the index is built the window is numbered exactly
+	newModel, cmd := model.Update(lineIndexMsg{index: idx})
+	model = runCmd(newModel.(Model), cmd)
+
+	if model.approxLineNumbers {
+		t.Fatal("Expected exact line numbers once the index is built")
+	}
+	for _, line := range model.lines {
+		if !strings.Contains(line.RawLine, fmt.Sprintf(`"line": %d,`, line.LineNumber)) {
+			t.Fatalf("Line %d has the content of another line: %q", line.LineNumber, line.RawLine)
+		}
+	}
+	if model.lastLineNum != 5000 {
+		t.Errorf("Expected last line 5000, got %d", model.lastLineNum)
+	}
+}
+
+// TestPrevLinesReachStart tests that reading backwards to the start fixes estimated numbers
+func TestPrevLinesReachStart(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(generateLogContent(1500)))
+	lines, approx, tail, err := loadTailWindow(path, 1000)
+	if err != nil || !approx {
+		t.Fatalf("Expected an estimated window, got %v", err)
+	}
+	tail.Close()
+
+	model := Model{
+		filename:          path,
+		lines:             lines,
+		lastLineNum:       lines[len(lines)-1].LineNumber,
+		isFileFullyLoaded: true,
+		approxLineNumbers: true,
+		height:            20,
+		width:             80,
+	}
+
+	cmd := model.loadPrevLinesIfNeeded()
+	if cmd == nil {
+		t.Fatal("Expected the lines above to be read backwards")
+	}
+	newModel, _ := model.Update(cmd())
+	model = newModel.(Model)
+
+	if model.approxLineNumbers || model.hasLinesAbove() {
+		t.Fatal("Expected exact line numbers once the start of the file is reached")
+	}
+	if len(model.lines) != 1500 || model.lastLineNum != 1500 {
+		t.Errorf("Expected lines 1 to 1500, got %d lines ending at %d", len(model.lines), model.lastLineNum)
+	}
+	if got := model.getVisibleLines()[model.cursor].LineNumber; got != 501 {
+		t.Errorf("Expected the cursor to stay on line 501, got %d", got)
+	}
+}
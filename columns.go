@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/itchyny/gojq"
+)
+
+const (
+	defaultColumnWidth = 20  // Width of a column when there are no lines to fit it to
+	maxAutoColumnWidth = 40  // Widest a column is made to fit the lines
+	maxColumnWidth     = 200 // Widest a column can be resized to
+	columnSampleSize   = 200 // How many lines a new column's width is fitted to
+	columnGap          = "  "
+)
+
+var columnHeaderStyle = lipgloss.NewStyle().
+	Bold(true).
+	Underline(true).
+	Padding(0, 1)
+
+// cellReplacer keeps a cell on one line
+var cellReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// column is a field shown as a column of the table
+type column struct {
+	title      string
+	expression string // jq expression of the field
+	query      *gojq.Query
+	width      int // Fitted to the lines when 0
+}
+
+// newColumn returns a column for a field name or jq path, such as level or
+// .request.method
+func newColumn(name string, width int) (column, error) {
+	expression := name
+	if !strings.HasPrefix(name, ".") {
+		expression = keyPath("", name)
+	}
+	query, err := parseViewExpression(expression)
+	if err != nil {
+		return column{}, fmt.Errorf("column %s: %w", name, err)
+	}
+	title := strings.TrimPrefix(name, ".")
+	if title == "" {
+		title = name
+	}
+	return column{title: title, expression: expression, query: query, width: width}, nil
+}
+
+// parseColumns parses the -columns flag, field names or paths separated by
+// commas, each with an optional :width
+func parseColumns(spec string) ([]column, error) {
+	var columns []column
+	for _, item := range strings.Split(spec, ",") {
+		name, width := strings.TrimSpace(item), 0
+		if i := strings.LastIndex(name, ":"); i > 0 {
+			if n, err := strconv.Atoi(name[i+1:]); err == nil {
+				if n < 1 || n > maxColumnWidth {
+					return nil, fmt.Errorf("column %s: width must be between 1 and %d", name[:i], maxColumnWidth)
+				}
+				name, width = name[:i], n
+			}
+		}
+		if name == "" {
+			continue
+		}
+		col, err := newColumn(name, width)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// cell returns a column's value for a line, blank when the line doesn't have it
+func (c column) cell(line LogLine) string {
+	value, err := transformLine(c.query, line.data())
+	if err != nil {
+		return "ERROR"
+	}
+	if value == "null" {
+		return ""
+	}
+	return cellReplacer.Replace(value)
+}
+
+// fitCell cuts or pads text to a column's width
+func fitCell(text string, width int) string {
+	width = max(width, 1)
+	length := utf8.RuneCountInString(text)
+	if length > width {
+		return string([]rune(text)[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-length)
+}
+
+// truncateText cuts text longer than width characters short, ending it with ...
+func truncateText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	return string([]rune(text)[:max(width-3, 0)]) + "..."
+}
+
+// tableRow returns a line as a row of the table
+func tableRow(columns []column, line LogLine) string {
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		cells[i] = fitCell(c.cell(line), c.width)
+	}
+	return strings.TrimRight(strings.Join(cells, columnGap), " ")
+}
+
+// tableHeader returns the titles of the columns, lined up with the rows
+func tableHeader(columns []column) string {
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = fitCell(c.title, c.width)
+	}
+	return strings.TrimRight(strings.Join(titles, columnGap), " ")
+}
+
+// shownColumns returns the columns from the first one scrolled to
+func (m Model) shownColumns() []column {
+	return m.columns[min(m.columnOffset, len(m.columns)):]
+}
+
+// fitColumn sets the width of a column that has none to its widest value in
+// the lines around the cursor
+func (m Model) fitColumn(c column) column {
+	if c.width > 0 {
+		return c
+	}
+	visibleLines := m.getVisibleLines()
+	start := min(m.viewport, len(visibleLines))
+	sample := visibleLines[start:min(start+columnSampleSize, len(visibleLines))]
+
+	c.width = utf8.RuneCountInString(c.title)
+	widest := 0
+	for _, line := range sample {
+		if line.IsValid {
+			widest = max(widest, utf8.RuneCountInString(c.cell(line)))
+		}
+	}
+	if len(sample) == 0 {
+		widest = defaultColumnWidth
+	}
+	c.width = max(c.width, min(widest, maxAutoColumnWidth), 1)
+	return c
+}
+
+// setColumns shows lines as a table of the given columns, or as they were
+// without any
+func (m *Model) setColumns(columns []column) {
+	m.columns = make([]column, len(columns))
+	for i, c := range columns {
+		m.columns[i] = m.fitColumn(c)
+	}
+	m.columnOffset = 0
+}
+
+// scrollColumns scrolls the table sideways by whole columns
+func (m *Model) scrollColumns(delta int) {
+	m.columnOffset = max(0, min(m.columnOffset+delta, len(m.columns)-1))
+}
+
+// columnFields returns the paths of the values in the line under the cursor
+// that aren't columns yet, offered in the column picker
+func (m Model) columnFields() []string {
+	visibleLines := m.getVisibleLines()
+	if m.cursor >= len(visibleLines) || !visibleLines[m.cursor].IsValid {
+		return nil
+	}
+	root, err := parseJSONTree(visibleLines[m.cursor].RawLine)
+	if err != nil {
+		return nil
+	}
+	tree := prettyTree{root: root}
+	var fields []string
+	for _, node := range tree.nodes() {
+		if node == root || node.expandable() || m.hasColumn(node.path()) {
+			continue
+		}
+		fields = append(fields, node.path())
+	}
+	return fields
+}
+
+// hasColumn reports whether a jq path is one of the columns
+func (m Model) hasColumn(expression string) bool {
+	for _, c := range m.columns {
+		if c.expression == expression {
+			return true
+		}
+	}
+	return false
+}
+
+// openColumnPicker lists the columns and the other fields of the line under
+// the cursor to choose from
+func (m *Model) openColumnPicker() {
+	m.columnManageMode = true
+	m.columnPickerFields = m.columnFields()
+	m.columnCursor = 0
+}
+
+// toggleColumn turns the field under the picker's cursor into a column at
+// the end of the table, or a column back into a field, keeping the cursor on it
+func (m *Model) toggleColumn() {
+	i := m.columnCursor
+	if i < len(m.columns) {
+		expression := m.columns[i].expression
+		m.columns = append(m.columns[:i:i], m.columns[i+1:]...)
+		m.columnPickerFields = append([]string{expression}, m.columnPickerFields...)
+		m.columnCursor = len(m.columns)
+	} else if i-len(m.columns) < len(m.columnPickerFields) {
+		field := i - len(m.columns)
+		c, err := newColumn(m.columnPickerFields[field], 0)
+		if err != nil {
+			return // Paths of values in a line are always valid
+		}
+		m.columns = append(m.columns, m.fitColumn(c))
+		m.columnPickerFields = append(m.columnPickerFields[:field:field], m.columnPickerFields[field+1:]...)
+		m.columnCursor = len(m.columns) - 1
+	}
+	m.columnOffset = min(m.columnOffset, max(len(m.columns)-1, 0))
+}
+
+// moveColumn moves the column under the picker's cursor left or right in the table
+func (m *Model) moveColumn(delta int) {
+	i, j := m.columnCursor, m.columnCursor+delta
+	if i >= len(m.columns) || j < 0 || j >= len(m.columns) {
+		return
+	}
+	m.columns[i], m.columns[j] = m.columns[j], m.columns[i]
+	m.columnCursor = j
+}
+
+// resizeColumn makes the column under the picker's cursor wider or narrower
+func (m *Model) resizeColumn(delta int) {
+	if m.columnCursor < len(m.columns) {
+		c := &m.columns[m.columnCursor]
+		c.width = max(1, min(c.width+delta, maxColumnWidth))
+	}
+}
+
+// renderColumnManageView renders the column picker
+func (m Model) renderColumnManageView() string {
+	var s strings.Builder
+
+	// Calculate available space
+	statusLines := 1
+	availableLines := m.height - statusLines
+	if availableLines < 1 {
+		availableLines = 1
+	}
+
+	s.WriteString("Columns (ENTER/SPACE to toggle, K/J to move, </> to resize, c/ESC to exit):")
+	s.WriteString("\n\n")
+	contentLines := 2
+
+	total := len(m.columns) + len(m.columnPickerFields)
+	if total == 0 {
+		s.WriteString("No fields in the line under the cursor.")
+		s.WriteString("\n")
+		contentLines++
+	}
+
+	// Keep the cursor on screen when there are more fields than fit
+	start := max(0, m.columnCursor-(availableLines-contentLines)+1)
+	for i := start; i < total && contentLines < availableLines; i++ {
+		prefix := "  "
+		style := lineStyle
+		if i == m.columnCursor {
+			prefix = "> "
+			style = selectedLineStyle
+		}
+
+		var line string
+		if i < len(m.columns) {
+			line = fmt.Sprintf("%s[✓] %s (width %d)", prefix, m.columns[i].expression, m.columns[i].width)
+		} else {
+			line = fmt.Sprintf("%s[ ] %s", prefix, m.columnPickerFields[i-len(m.columns)])
+		}
+
+		// Truncate if too long
+		if m.width > 5 {
+			line = truncateText(line, m.width-2)
+		}
+
+		s.WriteString(style.Render(line))
+		s.WriteString("\n")
+		contentLines++
+	}
+
+	// Fill remaining space
+	for contentLines < availableLines {
+		s.WriteString("\n")
+		contentLines++
+	}
+
+	statusText := fmt.Sprintf("%s | %d columns", m.filename, len(m.columns))
+	s.WriteString(statusStyle.Width(m.width - 1).Render(statusText))
+	return s.String()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// columnLines returns request logs of different lengths
+func columnLines() []LogLine {
+	return parseLines(
+		`{"ts": "10:30:00", "level": "info", "service": "api", "msg": "started"}`,
+		`{"ts": "10:30:01", "level": "error", "service": "billing-worker", "msg": "card declined\nretrying"}`,
+		`not json at all`,
+		`{"ts": "10:30:02", "level": "warn", "msg": "slow", "request": {"method": "GET"}}`,
+	)
+}
+
+// TestParseColumns tests the names, paths and widths given to -columns
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns("ts, level:5,.request.method,x-id:8,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		title, expression string
+		width             int
+	}{
+		{"ts", ".ts", 0},
+		{"level", ".level", 5},
+		{"request.method", ".request.method", 0},
+		{"x-id", `.["x-id"]`, 8},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("Expected %d columns, got %d", len(want), len(columns))
+	}
+	for i, w := range want {
+		c := columns[i]
+		if c.title != w.title || c.expression != w.expression || c.width != w.width {
+			t.Errorf("Expected %+v, got %s %s %d", w, c.title, c.expression, c.width)
+		}
+	}
+
+	for _, spec := range []string{"level:0", ".a[", "msg:500"} {
+		if _, err := parseColumns(spec); err == nil {
+			t.Errorf("Expected %q to fail", spec)
+		}
+	}
+}
+
+// TestFitCell tests cutting and padding cells to their width
+func TestFitCell(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"info", 6, "info  "},
+		{"error", 5, "error"},
+		{"billing-worker", 8, "billing…"},
+		{"héllo wörld", 6, "héllo…"},
+		{"x", 0, "x"},
+	}
+	for _, tt := range tests {
+		if got := fitCell(tt.text, tt.width); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+// TestTableView tests that columns line up under the header and scroll sideways
+func TestTableView(t *testing.T) {
+	model := testModel(columnLines())
+	columns, err := parseColumns("ts,level,service:8,msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.setColumns(columns)
+	if model.columns[0].width != 8 || model.columns[1].width != 5 {
+		t.Errorf("Expected the widths fitted to the values, got %d and %d", model.columns[0].width, model.columns[1].width)
+	}
+
+	lines := strings.Split(model.View(), "\n")
+	if !strings.Contains(lines[0], "ts        level  service   msg") {
+		t.Errorf("Expected the header, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "10:30:00  info   api       started") {
+		t.Errorf("Expected the first row lined up, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "10:30:01  error  billing…  card declined retrying") {
+		t.Errorf("Expected the second row cut to the widths, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "not json at all") {
+		t.Errorf("Expected the invalid line as it is, got %q", lines[3])
+	}
+	if !strings.Contains(lines[4], "10:30:02  warn             slow") {
+		t.Errorf("Expected a blank cell for the missing service, got %q", lines[4])
+	}
+
+	model = pressSpecialKey(model, tea.KeyRight)
+	lines = strings.Split(model.View(), "\n")
+	if !strings.Contains(lines[0], "level  service   msg") || strings.Contains(lines[0], "ts ") {
+		t.Errorf("Expected the table scrolled a column, got %q", lines[0])
+	}
+
+	// Searching searches the rows as shown
+	model, _ = typeSearch(model, '/', "error  billing")
+	if got := model.cursorLineNumber(); got != 2 {
+		t.Errorf("Expected the search to find the row, got line %d", got)
+	}
+}
+
+// TestTableRowMultiByte tests that rows are measured in characters, not bytes
+func TestTableRowMultiByte(t *testing.T) {
+	model := testModel(parseLines(`{"a": "` + strings.Repeat("€", 29) + `", "b": "last"}`))
+	model.width = 80
+	columns, err := parseColumns("a:30,b:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.setColumns(columns)
+
+	row := stripANSI(strings.Split(model.View(), "\n")[1])
+	if !utf8.ValidString(row) || !strings.Contains(row, strings.Repeat("€", 29)+"   last") {
+		t.Errorf("Expected both columns whole, got %q", row)
+	}
+}
+
+// TestTableKeepsCursorOnScreen tests that the header doesn't push the cursor off the screen
+func TestTableKeepsCursorOnScreen(t *testing.T) {
+	model := testModel(numberedLines(50))
+	model.height = 5
+	columns, err := parseColumns("line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.setColumns(columns)
+	for range 10 {
+		model, _ = pressKey(model, "j")
+	}
+	lines := strings.Split(model.View(), "\n")
+	if !strings.HasPrefix(strings.TrimSpace(lines[len(lines)-2]), "> 11") {
+		t.Errorf("Expected the cursor on the last line shown, got %q", lines[len(lines)-2])
+	}
+}
+
+// TestColumnPicker tests picking, moving and resizing columns
+func TestColumnPicker(t *testing.T) {
+	model := testModel(columnLines())
+	model.cursor = 3
+	model, _ = pressKey(model, "c")
+	if !model.columnManageMode {
+		t.Fatal("Expected the column picker")
+	}
+	want := []string{".ts", ".level", ".msg", ".request.method"}
+	if strings.Join(model.columnPickerFields, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected the fields of the line, got %v", model.columnPickerFields)
+	}
+
+	// Add msg, then level, and move level first
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, " ")
+	if len(model.columns) != 2 || model.columnCursor != 1 {
+		t.Fatalf("Expected two columns with the cursor on the last, got %d at %d", len(model.columns), model.columnCursor)
+	}
+	model, _ = pressKey(model, "K")
+	model, _ = pressKey(model, ">")
+	model, _ = pressKey(model, ">")
+	if model.columns[0].title != "level" || model.columns[0].width != 7 {
+		t.Errorf("Expected level first and wider, got %s %d", model.columns[0].title, model.columns[0].width)
+	}
+	if !strings.Contains(model.View(), "[✓] .level (width 7)") {
+		t.Error("Expected the column and its width in the picker")
+	}
+
+	// Removing a column keeps the cursor on it
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, " ")
+	if len(model.columns) != 1 || model.columnCursor != 1 || model.columnPickerFields[0] != ".msg" {
+		t.Errorf("Expected msg back among the fields, got %v", model.columnPickerFields)
+	}
+
+	model, _ = pressKey(model, "c")
+	if model.columnManageMode {
+		t.Fatal("Expected the picker closed")
+	}
+	if header := strings.Split(model.View(), "\n")[0]; !strings.Contains(header, "level") {
+		t.Errorf("Expected the level column, got %q", header)
+	}
+}
+
+// TestColumnsTruncatedByRunes tests that long fields are cut short between
+// characters, in the picker and in the table's header
+func TestColumnsTruncatedByRunes(t *testing.T) {
+	model := testModel(parseLines(`{"ユーザー名": "ann", "説明": "x"}`))
+	model.width = 17
+	wholeCharacters := func(view string) {
+		t.Helper()
+		for _, line := range strings.Split(stripANSI(view), "\n") {
+			if !utf8.ValidString(line) || strings.ContainsRune(line, utf8.RuneError) {
+				t.Errorf("Expected whole characters, got %q", line)
+			}
+		}
+	}
+
+	model, _ = pressKey(model, "c")
+	if !model.columnManageMode {
+		t.Fatal("Expected the column picker")
+	}
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, " ")
+	wholeCharacters(model.View())
+
+	model, _ = pressKey(model, "c")
+	if len(model.columns) != 2 {
+		t.Fatalf("Expected both columns, got %d", len(model.columns))
+	}
+	wholeCharacters(model.View())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxContextLines is the most lines shown around each match
+const maxContextLines = 99
+
+var (
+	contextLineStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#808080")).
+				Faint(true).
+				Padding(0, 1)
+
+	separatorLineStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#4A90E2")).
+				Padding(0, 1)
+)
+
+// contextCache holds the lines shown with context, shared by every copy of
+// the model so it's only worked out again when its inputs change
+type contextCache struct {
+	key   contextKey
+	lines []LogLine
+}
+
+// contextKey identifies what the lines shown with context were worked out
+// from. Line numbers are kept too, as they can be shifted in place.
+type contextKey struct {
+	lines, filtered          *LogLine // Start of each slice, nil when empty
+	lineCount, filteredCount int
+	firstLine, firstFiltered int
+	context                  int
+}
+
+// newContextKey returns the key for lines and filtered shown with context lines around
+func newContextKey(lines, filtered []LogLine, context int) contextKey {
+	key := contextKey{lineCount: len(lines), filteredCount: len(filtered), context: context}
+	if len(lines) > 0 {
+		key.lines = &lines[0]
+		key.firstLine = lines[0].LineNumber
+	}
+	if len(filtered) > 0 {
+		key.filtered = &filtered[0]
+		key.firstFiltered = filtered[0].LineNumber
+	}
+	return key
+}
+
+// reset forgets the lines, after lines were changed in a way the key can't spot
+func (c *contextCache) reset() {
+	if c == nil {
+		return
+	}
+	c.key = contextKey{}
+	c.lines = nil
+}
+
+// linesWithContext returns the filtered lines with the lines around them
+func (m Model) linesWithContext() []LogLine {
+	if m.contextCache == nil {
+		return withContext(m.lines, m.filteredLines, m.contextLines)
+	}
+	key := newContextKey(m.lines, m.filteredLines, m.contextLines)
+	if m.contextCache.lines == nil || m.contextCache.key != key {
+		m.contextCache.key = key
+		m.contextCache.lines = withContext(m.lines, m.filteredLines, m.contextLines)
+	}
+	return m.contextCache.lines
+}
+
+// withContext returns matches, which are in order and among lines, with up
+// to context lines before and after each one. Lines that are only shown as
+// context are marked, and a separator goes between groups that aren't next
+// to each other, numbered like the line after it so the cursor lands on lines.
+func withContext(lines, matches []LogLine, context int) []LogLine {
+	out := make([]LogLine, 0, len(matches))
+	addContext := func(from, to int) {
+		for _, line := range lines[from:to] {
+			line.Context = true
+			line.JSONData = nil // Decoded again when needed, so eviction isn't held up
+			out = append(out, line)
+		}
+	}
+
+	shown := 0 // Lines before this one in lines were shown or skipped
+	after := 0 // Lines before this one follow the last match closely enough to be shown
+	i := 0
+	for _, match := range matches {
+		for i < len(lines) && lines[i].LineNumber < match.LineNumber {
+			i++
+		}
+
+		// The lines after the last match, then those before this one
+		if tail := min(after, i); tail > shown {
+			addContext(shown, tail)
+			shown = tail
+		}
+		from := max(shown, i-context)
+		if from > shown && len(out) > 0 {
+			next := match.LineNumber
+			if from < i {
+				next = lines[from].LineNumber
+			}
+			out = append(out, LogLine{LineNumber: next, Separator: true})
+		}
+		addContext(from, i)
+
+		out = append(out, match)
+		if i < len(lines) && lines[i].LineNumber == match.LineNumber {
+			i++
+		}
+		shown = i
+		after = i + context
+	}
+	addContext(shown, min(after, len(lines)))
+	return out
+}
+
+// setContextLines changes how many lines are shown around each match,
+// keeping the cursor on the same line
+func (m *Model) setContextLines(context int) {
+	context = max(0, min(context, maxContextLines))
+	if context == m.contextLines {
+		return
+	}
+	target := m.cursorLineNumber()
+	m.contextLines = context
+	m.restorePositionAfterFilter(target)
+}
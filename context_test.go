@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// contextString describes lines shown with context, e.g. "1 [2] -- [4] 5"
+// with context lines in brackets and separators as --
+func contextString(lines []LogLine) string {
+	var parts []string
+	for _, line := range lines {
+		switch {
+		case line.Separator:
+			parts = append(parts, "--")
+		case line.Context:
+			parts = append(parts, fmt.Sprintf("[%d]", line.LineNumber))
+		default:
+			parts = append(parts, fmt.Sprint(line.LineNumber))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// TestWithContext tests which lines are shown around matches
+func TestWithContext(t *testing.T) {
+	var lines []LogLine
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, LogLine{LineNumber: i})
+	}
+	pick := func(numbers ...int) []LogLine {
+		var picked []LogLine
+		for _, n := range numbers {
+			picked = append(picked, lines[n-1])
+		}
+		return picked
+	}
+
+	tests := []struct {
+		name    string
+		matches []LogLine
+		context int
+		want    string
+	}{
+		{"no context", pick(3, 8), 0, "3 -- 8"},
+		{"apart", pick(3, 9), 1, "[2] 3 [4] -- [8] 9 [10]"},
+		{"touching", pick(3, 6), 1, "[2] 3 [4] [5] 6 [7]"},
+		{"overlapping", pick(3, 5), 2, "[1] [2] 3 [4] 5 [6] [7]"},
+		{"next to each other", pick(4, 5, 6), 1, "[3] 4 5 6 [7]"},
+		{"ends of the file", pick(1, 12), 2, "1 [2] [3] -- [10] [11] 12"},
+		{"no matches", nil, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contextString(withContext(lines, tt.matches, tt.context)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestContextLines tests adjusting context with the keys and how it's shown
+func TestContextLines(t *testing.T) {
+	model := testModel(numberedLines(100))
+	model.contextCache = &contextCache{}
+	model.filters = []Filter{mustFilter(t, ".line % 10 == 0")}
+	model.applyFilters()
+	model.restorePositionAfterFilter(30)
+
+	model, _ = pressKey(model, "+")
+	model, _ = pressKey(model, "+")
+	if model.contextLines != 2 {
+		t.Fatalf("Expected 2 lines of context, got %d", model.contextLines)
+	}
+	if got := model.cursorLineNumber(); got != 30 {
+		t.Errorf("Expected the cursor to stay on line 30, got %d", got)
+	}
+	visible := model.getVisibleLines()
+	if got := contextString(visible[:7]); got != "[8] [9] 10 [11] [12] -- [18]" {
+		t.Errorf("Unexpected lines shown: %s", got)
+	}
+
+	view := model.View()
+	if !strings.Contains(view, "  --") {
+		t.Error("Expected a separator between groups")
+	}
+	if !strings.Contains(view, "C=2") {
+		t.Error("Expected the context in the status bar")
+	}
+	if !strings.Contains(view, "Line 30/10") {
+		t.Error("Expected the total to count matches only")
+	}
+
+	// Lines appended later get context too
+	model.appendNewLines([]LogLine{
+		parseLogLine(101, `{"line": 101}`),
+		parseLogLine(102, `{"line": 102}`),
+		parseLogLine(103, `{"line": 103}`),
+		parseLogLine(104, `{"line": 104}`),
+	})
+	visible = model.getVisibleLines()
+	if got := contextString(visible[len(visible)-4:]); got != "[99] 100 [101] [102]" {
+		t.Errorf("Expected context after the last match, got %s", got)
+	}
+
+	model, _ = pressKey(model, "-")
+	model, _ = pressKey(model, "-")
+	model, _ = pressKey(model, "-")
+	if model.contextLines != 0 || len(model.getVisibleLines()) != 10 {
+		t.Errorf("Expected only the matches without context, got %d lines", len(model.getVisibleLines()))
+	}
+	if got := model.cursorLineNumber(); got != 30 {
+		t.Errorf("Expected the cursor to stay on line 30, got %d", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/itchyny/gojq"
+)
+
+// exprError is a filter or view expression that couldn't be parsed or compiled
+type exprError struct {
+	column int // Where in the expression the problem is, from 1, or 0 if unknown
+	err    error
+}
+
+func (e *exprError) Error() string {
+	if e.column > 0 {
+		return fmt.Sprintf("column %d: %v", e.column, e.err)
+	}
+	return e.err.Error()
+}
+
+func (e *exprError) Unwrap() error {
+	return e.err
+}
+
+// newExprError describes why expression couldn't be parsed or compiled,
+// finding the column of the problem where it can
+func newExprError(expression string, err error) error {
+	e := &exprError{err: err}
+
+	var parseErr *gojq.ParseError
+	if errors.As(err, &parseErr) {
+		// The offset is just past the token that couldn't be parsed
+		e.column = parseErr.Offset - len(parseErr.Token) + 1
+	} else if _, name, ok := strings.Cut(err.Error(), "not defined: "); ok {
+		// Undefined functions are reported as name/arity
+		if slash := strings.LastIndexByte(name, '/'); slash > 0 {
+			name = name[:slash]
+		}
+		if i := strings.Index(expression, name); i >= 0 {
+			e.column = i + 1
+		}
+	}
+	if e.column > len(expression)+1 {
+		e.column = len(expression) + 1
+	}
+	return e
+}
+
+// errorColumn returns the column an expression error is at, or 0 if unknown
+func errorColumn(err error) int {
+	var e *exprError
+	if errors.As(err, &e) {
+		return e.column
+	}
+	return 0
+}
+
+// showInputError keeps an input open after its expression failed, moving
+// the cursor to the problem
+func (m *Model) showInputError(err error, input string, cursorPos *int) {
+	m.inputErr = err
+	if column := errorColumn(err); column > 0 {
+		*cursorPos = column - 1
+		if *cursorPos > len(input) {
+			*cursorPos = len(input)
+		}
+	}
+}
+
+// inputErrorText returns what follows an input that failed, empty if none did
+func (m Model) inputErrorText() string {
+	if m.inputErr == nil {
+		return ""
+	}
+	return "  Error: " + m.inputErr.Error()
+}
+
+// filterErrors counts the lines a filter failed on at runtime, shared by
+// every copy of the filter and safe to update from any goroutine
+type filterErrors struct {
+	mu     sync.Mutex
+	count  int
+	sample LogLine // First line the filter failed on, by line number
+	err    error   // Why it failed on sample
+}
+
+// record counts a line the filter failed on
+func (e *filterErrors) record(line LogLine, err error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// Lines are checked by several workers, so failures aren't recorded in order
+	if e.count == 0 || line.LineNumber < e.sample.LineNumber {
+		e.sample = line
+		e.err = err
+	}
+	e.count++
+}
+
+// reset forgets failures, before every line is filtered again
+func (e *filterErrors) reset() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.count = 0
+	e.sample = LogLine{}
+	e.err = nil
+}
+
+// summary returns how many lines the filter failed on, with the first one
+func (e *filterErrors) summary() (count int, sample LogLine, err error) {
+	if e == nil {
+		return 0, LogLine{}, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.count, e.sample, e.err
+}
+
+// resetFilterErrors forgets the failures of every filter
+func resetFilterErrors(filters []Filter) {
+	for _, filter := range filters {
+		filter.failures.reset()
+	}
+}
+
+// countFailuresAfresh returns a copy of filters that counts failures from
+// scratch, leaving the counts of the filters copied as they are
+func countFailuresAfresh(filters []Filter) []Filter {
+	fresh := append([]Filter(nil), filters...)
+	for i := range fresh {
+		fresh[i].failures = &filterErrors{}
+	}
+	return fresh
+}
+
+// filterErrorCount returns how many failures the filters have had in all
+func (m Model) filterErrorCount() int {
+	total := 0
+	for _, filter := range m.filters {
+		count, _, _ := filter.failures.summary()
+		total += count
+	}
+	return total
+}
+
+// parseViewExpression parses and compiles a view expression, so undefined
+// functions and variables are caught before it's shown
+func parseViewExpression(expression string) (*gojq.Query, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, newExprError(expression, err)
+	}
+	if _, err := gojq.Compile(query); err != nil {
+		return nil, newExprError(expression, err)
+	}
+	return query, nil
+}
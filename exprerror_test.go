@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestNewExprErrorColumn tests finding where an expression went wrong
+func TestNewExprErrorColumn(t *testing.T) {
+	tests := []struct {
+		expression string
+		column     int
+	}{
+		{".foo |", 7},            // Ends too early
+		{".a ] .b", 4},           // Unexpected token
+		{".a | nope", 6},         // Undefined function
+		{".a | nope(1)", 6},      // Undefined function with arguments
+		{".a == $missing", 7},    // Undefined variable
+		{`.a | "open`, 11},       // Unterminated string, where it runs out
+		{".a | error(", 12},      // Unclosed call
+		{"select(.a) | .b[", 17}, // Unclosed index
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := newFilter(tt.expression)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if got := errorColumn(err); got != tt.column {
+				t.Errorf("Expected column %d, got %d (%v)", tt.column, got, err)
+			}
+		})
+	}
+}
+
+// TestFilterInputError tests that a filter that doesn't parse keeps the input open
+func TestFilterInputError(t *testing.T) {
+	model := testModel(numberedLines(10))
+	model, cmd := typeFilter(model, ".line ] 2")
+
+	if cmd != nil || !model.filterMode || len(model.filters) != 0 {
+		t.Fatal("Expected the input to stay open without adding the filter")
+	}
+	if model.filterCursorPos != 6 {
+		t.Errorf("Expected the cursor on the bad token, got %d", model.filterCursorPos)
+	}
+	if !strings.Contains(model.View(), `Error: column 7: unexpected token "]"`) {
+		t.Error("Expected the error in the filter input")
+	}
+
+	// Typing clears it
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model = newModel.(Model)
+	if model.inputErr != nil {
+		t.Error("Expected the error to clear once the input changes")
+	}
+
+	// Editing keeps the edit open the same way
+	model.filterMode = false
+	model.filters = []Filter{mustFilter(t, ".even")}
+	model.filterManageMode = true
+	model.filterEditMode = true
+	model.filterEditInput = ".even and"
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if !model.filterEditMode || model.inputErr == nil || model.filters[0].Expression != ".even" {
+		t.Error("Expected the edit to stay open and the filter unchanged")
+	}
+}
+
+// TestFilterRuntimeErrors tests that lines a filter fails on are counted and shown
+func TestFilterRuntimeErrors(t *testing.T) {
+	model := testModel(parseLines(
+		`{"msg": "connected"}`,
+		`{"msg": 42}`,
+		`{"msg": "closed"}`,
+		`{"msg": 7}`,
+	))
+	model.filters = []Filter{mustFilter(t, `.msg | test("c")`)}
+	model.applyFilters()
+
+	count, sample, err := model.filters[0].failures.summary()
+	if count != 2 || sample.LineNumber != 2 || err == nil {
+		t.Fatalf("Expected 2 failures starting at line 2, got %d at line %d (%v)", count, sample.LineNumber, err)
+	}
+	if !strings.Contains(model.View(), "2 filter errors") {
+		t.Error("Expected the failures in the status bar")
+	}
+
+	model.filterManageMode = true
+	view := model.View()
+	if !strings.Contains(view, "2 lines failed") || !strings.Contains(view, `e.g. line 2: {"msg": 42}`) {
+		t.Errorf("Expected the failures in Filter Management, got:\n%s", view)
+	}
+
+	// Filtering everything again counts from scratch
+	model.applyFilters()
+	if count, _, _ := model.filters[0].failures.summary(); count != 2 {
+		t.Errorf("Expected 2 failures after filtering again, got %d", count)
+	}
+}
+
+// TestViewTransformError tests that lines the view fails on say why
+func TestViewTransformError(t *testing.T) {
+	model := testModel(parseLines(`{"msg": "hello"}`))
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	model = newModel.(Model)
+	model.viewInput = ".msg | nope"
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if !model.viewMode || !strings.Contains(model.View(), "function not defined: nope/0") {
+		t.Fatal("Expected a view that doesn't compile to keep the input open")
+	}
+
+	model.viewInput = ".msg | keys"
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.viewMode {
+		t.Fatal("Expected the view to be applied")
+	}
+	if view := model.View(); !strings.Contains(view, "[VIEW ERROR:") || !strings.Contains(view, `{"msg": "hello"}`) {
+		t.Errorf("Expected the raw line with the view's error, got:\n%s", view)
+	}
+}
+
+// mustFilter parses a filter or fails the test
+func mustFilter(t *testing.T, expression string) Filter {
+	t.Helper()
+	filter, err := newFilter(expression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+// TestFilterErrorSample tests that the sample is the first failing line
+// however the workers record them, and fits the screen by characters
+func TestFilterErrorSample(t *testing.T) {
+	failures := &filterErrors{}
+	failures.record(parseLogLine(9, `{"msg": 9}`), errors.New("later"))
+	failures.record(parseLogLine(4, `{"msg": "€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€€"}`), errors.New("first"))
+	count, sample, err := failures.summary()
+	if count != 2 || sample.LineNumber != 4 || err.Error() != "first" {
+		t.Fatalf("Expected line 4 as the sample, got line %d (%v)", sample.LineNumber, err)
+	}
+
+	filter := mustFilter(t, `.msg | test("€")`)
+	filter.failures = failures
+	model := testModel(nil)
+	model.filters = []Filter{filter}
+	model.filterManageMode = true
+	model.width = 40
+	view := model.View()
+	if strings.ContainsRune(view, utf8.RuneError) {
+		t.Errorf("Expected the sample cut between characters, got:\n%s", view)
+	}
+	if !strings.Contains(view, "e.g. line 4: {\"msg\": \"€€€") {
+		t.Errorf("Expected the sample in Filter Management, got:\n%s", view)
+	}
+}
+
+// TestFilterCancelKeepsErrors tests that cancelling goes back to the
+// failures counted before the change
+func TestFilterCancelKeepsErrors(t *testing.T) {
+	model := testModel(numberedLines(20000))
+	model.lines[15000] = parseLogLine(15001, `{"line": "15001"}`)
+	model.filters = []Filter{mustFilter(t, ".line + 0 > 10")}
+	model.applyFilters()
+	if count, _, _ := model.filters[0].failures.summary(); count != 1 {
+		t.Fatalf("Expected 1 failure, got %d", count)
+	}
+
+	// The job stops before it gets to the failing line
+	model, _ = typeFilter(model, ".even")
+	if model.filterJob == nil {
+		t.Fatal("Expected filtering to start in the background")
+	}
+	if count, _, _ := model.filters[0].failures.summary(); count != 0 {
+		t.Errorf("Expected the job to count failures from scratch, got %d", count)
+	}
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if count, _, _ := model.filters[0].failures.summary(); len(model.filters) != 1 || count != 1 {
+		t.Errorf("Expected the failure from before back, got %d", count)
+	}
+}
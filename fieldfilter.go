@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fieldFilterKind is what a filter made from a field matches
+type fieldFilterKind int
+
+const (
+	fieldEquals    fieldFilterKind = iota // Lines where the field has this value
+	fieldNotEquals                        // Lines where it doesn't
+	fieldExists                           // Lines where the field is present, even as null
+	fieldSameValue                        // Only lines sharing the value, other filters disabled
+)
+
+// fieldFilterKeys are the keys in the pretty view that make each kind of filter
+var fieldFilterKeys = map[string]fieldFilterKind{
+	"=": fieldEquals,
+	"!": fieldNotEquals,
+	"e": fieldExists,
+	"s": fieldSameValue,
+}
+
+// literal returns the node's value as a jq literal, compacted
+func (n *jsonNode) literal() string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(n.value)); err != nil {
+		return n.value
+	}
+	return buf.String()
+}
+
+// fieldFilterExpression returns the filter matching lines by a node's value
+func fieldFilterExpression(node *jsonNode, kind fieldFilterKind) string {
+	switch kind {
+	case fieldNotEquals:
+		return node.path() + " != " + node.literal()
+	case fieldExists:
+		return presenceTest(node)
+	default:
+		return node.path() + " == " + node.literal()
+	}
+}
+
+// presenceTest returns the filter matching lines where the node's key or
+// index is present in its parent, e.g. (.user | has("id"))
+func presenceTest(node *jsonNode) string {
+	var test string
+	if node.parent.kind == jsonArray {
+		test = "has(" + strconv.Itoa(node.index) + ")"
+	} else {
+		quoted, _ := json.Marshal(node.key)
+		test = "has(" + string(quoted) + ")"
+	}
+	if node.parent.parent == nil {
+		return test // A key of the line itself
+	}
+	return "(" + node.parent.path() + " | " + test + ")"
+}
+
+// filterOnField adds a filter on the value under the pretty view's cursor and
+// goes back to the lines, filtering them in the background. An expression
+// that can't be used is opened in the filter input to be fixed.
+func (m *Model) filterOnField(kind fieldFilterKind) tea.Cmd {
+	tree := m.tree()
+	if tree == nil || tree.cursor == tree.root {
+		return nil // Filters are on a field of the line, not the whole of it
+	}
+	expression := fieldFilterExpression(tree.cursor, kind)
+	m.closePretty()
+
+	previous := append([]Filter(nil), m.filters...)
+	if err := m.addFilter(expression); err != nil {
+		m.filterMode = true
+		m.filterInput = expression
+		m.filterCursorPos = len(expression)
+		m.showInputError(err, m.filterInput, &m.filterCursorPos)
+		return nil
+	}
+	if kind == fieldSameValue {
+		for i := range m.filters[:len(m.filters)-1] {
+			m.filters[i].Enabled = false
+		}
+	}
+
+	// Filter in the background, keeping the cursor on the same line
+	return m.refilter(previous)
+}
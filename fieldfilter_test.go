@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// traceLines returns the lines of two traces, interleaved
+func traceLines() []LogLine {
+	return parseLines(
+		`{"level": "info", "trace_id": "a1", "user": {"id": 1234}}`,
+		`{"level": "info", "trace_id": "b2", "user": {"id": 99}}`,
+		`{"level": "error", "trace_id": "a1", "user": {"id": 1234}}`,
+		`{"level": "info", "trace_id": "b2"}`,
+		`{"level": "info", "trace_id": "a1", "user": {"id": null}}`,
+	)
+}
+
+// openField opens the pretty view on the cursor's line and moves to the value at path
+func openField(t *testing.T, model Model, path string) Model {
+	t.Helper()
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, "E")
+	for model.prettyTree.cursor.path() != path {
+		before := model.prettyTree.cursor
+		model, _ = pressKey(model, "j")
+		if model.prettyTree.cursor == before {
+			t.Fatalf("No value at %s", path)
+		}
+	}
+	return model
+}
+
+// TestFieldFilterExpression tests the filters made from values
+func TestFieldFilterExpression(t *testing.T) {
+	root, err := parseJSONTree(`{"user": {"id": 1234, "name": "Ann \"A\""}, "tags": [ "a",  "b" ], "x-id": 1.50}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := root.children[0]
+	tests := []struct {
+		node *jsonNode
+		kind fieldFilterKind
+		want string
+	}{
+		{user.children[0], fieldEquals, ".user.id == 1234"},
+		{user.children[0], fieldNotEquals, ".user.id != 1234"},
+		{user.children[0], fieldExists, `(.user | has("id"))`},
+		{root.children[1].children[1], fieldExists, `(.tags | has(1))`},
+		{root.children[2], fieldExists, `has("x-id")`},
+		{user.children[1], fieldSameValue, `.user.name == "Ann \"A\""`},
+		{root.children[1], fieldEquals, `.tags == ["a","b"]`},
+		{root.children[2], fieldEquals, `.["x-id"] == 1.50`},
+	}
+	for _, tt := range tests {
+		if got := fieldFilterExpression(tt.node, tt.kind); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+		if _, err := newFilter(fieldFilterExpression(tt.node, tt.kind)); err != nil {
+			t.Errorf("Expected a valid filter, got %v", err)
+		}
+	}
+}
+
+// TestFilterOnField tests adding filters from the pretty view
+func TestFilterOnField(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []int
+	}{
+		{"=", []int{1, 3}},
+		{"!", []int{2, 4, 5}},
+		{"e", []int{1, 2, 3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			model := openField(t, testModel(traceLines()), ".user.id")
+			model, cmd := pressKey(model, tt.key)
+			if model.showPretty {
+				t.Error("Expected to be back at the lines")
+			}
+			model = runCmd(model, cmd)
+			if got := visibleLineNumbers(model); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected lines %v, got %v", tt.want, got)
+			}
+			if got := model.cursorLineNumber(); got != 1 && tt.key != "!" {
+				t.Errorf("Expected the cursor to stay on line 1, got %d", got)
+			}
+		})
+	}
+}
+
+// TestFilterOnSameValue tests showing only the lines that share a value
+func TestFilterOnSameValue(t *testing.T) {
+	model := testModel(traceLines())
+	model, cmd := typeFilter(model, `.level == "error"`)
+	model = runCmd(model, cmd)
+
+	model = openField(t, model, ".trace_id")
+	model, cmd = pressKey(model, "s")
+	model = runCmd(model, cmd)
+	if got := visibleLineNumbers(model); !slices.Equal(got, []int{1, 3, 5}) {
+		t.Errorf("Expected every line of the trace, got %v", got)
+	}
+	if len(model.filters) != 2 || model.filters[0].Enabled || !model.filters[1].Enabled {
+		t.Errorf("Expected the earlier filter disabled and the new one on, got %+v", model.filters)
+	}
+	if got := model.cursorLineNumber(); got != 3 {
+		t.Errorf("Expected the cursor to stay on line 3, got %d", got)
+	}
+
+	// Nothing to filter on with the cursor on the whole line
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, "=")
+	if !model.showPretty || len(model.filters) != 2 {
+		t.Error("Expected no filter on the whole line")
+	}
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if newModel.(Model).showPretty {
+		t.Error("Expected Esc to close the pretty view")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filterChunkSize is how many lines are checked between progress reports,
+// enough to keep every worker busy
+const filterChunkSize = 8192
+
+// filterJob filters a snapshot of the lines in memory in the background
+type filterJob struct {
+	lines   []LogLine // Lines being filtered, never modified while the job runs
+	filters []Filter
+	invalid invalidLineMode
+	checked int // Lines checked so far
+
+	target    int  // Line the cursor was on when filtering started
+	placed    int  // Where the cursor was last moved to while following target
+	following bool // Whether the cursor is kept on target as matches arrive
+
+	previous filterState // What cancelling goes back to
+}
+
+// filterState is what filtering some lines with some filters produced
+type filterState struct {
+	filters  []Filter
+	invalid  invalidLineMode
+	lines    []LogLine // Lines that were filtered
+	filtered []LogLine // Those that passed
+}
+
+// Message with the matches found in the next chunk of lines
+type filterProgressMsg struct {
+	seq     int
+	matches []LogLine
+	checked int // Lines checked so far, including this chunk
+}
+
+// run checks the lines chunk by chunk, reporting after each one
+func (j *filterJob) run(r jobRun) {
+	for start := 0; ; start += filterChunkSize {
+		end := start + filterChunkSize
+		if end > len(j.lines) {
+			end = len(j.lines)
+		}
+
+		matches, ok := filterLinesUntil(j.filters, j.invalid, j.lines[start:end], r.cancel)
+		if !ok {
+			return
+		}
+
+		if !r.send(filterProgressMsg{seq: r.seq, matches: matches, checked: end}) || end == len(j.lines) {
+			return
+		}
+	}
+}
+
+// percent returns how much of the job is done
+func (j *filterJob) percent() int {
+	if len(j.lines) == 0 {
+		return 100
+	}
+	return j.checked * 100 / len(j.lines)
+}
+
+// linesMark tells lines apart by their count and first and last lines, so
+// the lines themselves needn't be kept to spot changes to them
+type linesMark struct {
+	count                   int
+	firstNumber, lastNumber int
+	firstOffset, lastOffset int64
+}
+
+// markLines returns the mark of some lines
+func markLines(lines []LogLine) linesMark {
+	if len(lines) == 0 {
+		return linesMark{}
+	}
+	first, last := lines[0], lines[len(lines)-1]
+	return linesMark{
+		count:       len(lines),
+		firstNumber: first.LineNumber,
+		lastNumber:  last.LineNumber,
+		firstOffset: first.Offset,
+		lastOffset:  last.Offset,
+	}
+}
+
+// addedSince returns the lines appended to current since it held the marked
+// lines, or false when lines were loaded above, dropped or renumbered in between
+func (k linesMark) addedSince(current []LogLine) ([]LogLine, bool) {
+	if k.count == 0 {
+		return current, true
+	}
+	if len(current) < k.count || markLines(current[:k.count]) != k {
+		return nil, false
+	}
+	return current[k.count:], true
+}
+
+// linesAddedSince returns the lines appended to current since it held old,
+// or false when lines were loaded above, dropped or renumbered in between
+func linesAddedSince(old, current []LogLine) ([]LogLine, bool) {
+	return markLines(old).addedSince(current)
+}
+
+// linesShared reports whether a background job is reading m.lines, which
+// mustn't be changed in place until it's done
+func (m Model) linesShared() bool {
+	return m.filtering.running() || m.preview.counting.running() || m.scanning.running() || m.stats.computing.running()
+}
+
+// cursorLineNumber returns the number of the line under the cursor, 0 when nothing is shown
+func (m Model) cursorLineNumber() int {
+	visibleLines := m.getVisibleLines()
+	if m.cursor >= 0 && m.cursor < len(visibleLines) {
+		return visibleLines[m.cursor].LineNumber
+	}
+	return 0
+}
+
+// refilter filters the lines in memory again after the filters changed,
+// previousFilters being the filters from before the change
+func (m *Model) refilter(previousFilters []Filter) tea.Cmd {
+	return m.refilterFrom(filterState{
+		filters:  previousFilters,
+		invalid:  m.invalidLines,
+		lines:    m.lines,
+		filtered: m.filteredLines,
+	})
+}
+
+// refilterFrom filters the lines in memory again after the filters or how
+// they treat invalid lines changed, previous being what they were before
+func (m *Model) refilterFrom(previous filterState) tea.Cmd {
+	target := m.cursorLineNumber()
+	if j := m.filterJob; j != nil {
+		// Cancelling goes back to before the first of several quick changes
+		if j.following {
+			target = j.target
+		}
+		previous = j.previous
+		m.stopFilterJob()
+	}
+
+	if len(m.filters) == 0 {
+		m.filteredLines = m.lines
+		m.restorePositionAfterFilter(target)
+		m.enforceMemoryBudget()
+		return nil
+	}
+	return m.startFilterJob(target, previous)
+}
+
+// startFilterJob filters every line in memory in the background, starting
+// from an empty result
+func (m *Model) startFilterJob(target int, previous filterState) tea.Cmd {
+	j := &filterJob{
+		lines:     m.lines,
+		filters:   countFailuresAfresh(m.filters), // What cancelling goes back to keeps its counts
+		invalid:   m.invalidLines,
+		target:    target,
+		following: true,
+		previous:  previous,
+	}
+	m.filterJob = j
+	m.filters = append([]Filter(nil), j.filters...) // Filters are edited in place
+	m.filteredLines = nil
+	m.restorePositionAfterFilter(target)
+	j.placed = m.cursor
+	return m.filtering.start(j.run)
+}
+
+// stopFilterJob stops the running job, waiting until it no longer reads the lines
+func (m *Model) stopFilterJob() {
+	m.filtering.stop()
+	m.filterJob = nil
+}
+
+// handleFilterProgress adds the matches from a chunk, keeping the cursor on
+// the line it was on until it's moved by hand
+func (m *Model) handleFilterProgress(msg filterProgressMsg) tea.Cmd {
+	j := m.filterJob
+	m.filteredLines = append(m.filteredLines, msg.matches...)
+	j.checked = msg.checked
+
+	if j.following {
+		// Matches are only ever added below, so the cursor was moved by hand
+		// if it's not where it was left
+		if m.cursor == j.placed {
+			m.restorePositionAfterFilter(j.target)
+			j.placed = m.cursor
+		} else {
+			j.following = false
+		}
+	}
+
+	if j.checked == len(j.lines) {
+		return m.finishFilterJob()
+	}
+	return m.filtering.wait()
+}
+
+// finishFilterJob makes the job's result current once every line was checked
+func (m *Model) finishFilterJob() tea.Cmd {
+	j := m.filterJob
+	m.filtering.finish()
+	m.filterJob = nil
+
+	target := m.cursorLineNumber()
+	if j.following {
+		target = j.target
+	}
+	state := filterState{filters: j.filters, invalid: j.invalid, lines: j.lines, filtered: m.filteredLines}
+	cmd := m.resumeFilters(state, j.previous, target)
+	if m.filterJob == nil && m.tailMode {
+		m.moveCursorToEnd()
+	}
+	return cmd
+}
+
+// cancelFiltering stops the running job and goes back to the filters and
+// results from before the change
+func (m *Model) cancelFiltering() tea.Cmd {
+	j := m.filterJob
+	target := m.cursorLineNumber()
+	if j.following {
+		target = j.target
+	}
+	m.stopFilterJob()
+	return m.resumeFilters(j.previous, j.previous, target)
+}
+
+// resumeFilters makes state the current result, filtering only the lines
+// appended since, or starting over in the background when the lines in
+// memory were replaced. previous is what cancelling that goes back to.
+func (m *Model) resumeFilters(state, previous filterState, target int) tea.Cmd {
+	m.filters = append([]Filter(nil), state.filters...)
+	m.invalidLines = state.invalid
+	if len(m.filters) == 0 {
+		m.filteredLines = m.lines
+	} else if added, ok := linesAddedSince(state.lines, m.lines); ok {
+		filtered := state.filtered[:len(state.filtered):len(state.filtered)]
+		m.filteredLines = append(filtered, filterLines(m.filters, m.invalidLines, added)...)
+	} else {
+		return m.startFilterJob(target, previous)
+	}
+
+	m.restorePositionAfterFilter(target)
+	m.enforceMemoryBudget()
+	return nil
+}
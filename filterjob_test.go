@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// numberedLines returns n generated lines
+func numberedLines(n int) []LogLine {
+	lines := make([]LogLine, n)
+	for i := range lines {
+		lines[i] = parseLogLine(i+1, fmt.Sprintf(`{"line": %d, "even": %v}`, i+1, (i+1)%2 == 0))
+	}
+	return lines
+}
+
+// typeFilter adds a filter through the filter input and returns the command it started
+func typeFilter(model Model, expression string) (Model, tea.Cmd) {
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	model = newModel.(Model)
+	model.filterInput = expression
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return newModel.(Model), cmd
+}
+
+// TestFilterInBackground tests that matches stream in and the cursor stays on its line
+func TestFilterInBackground(t *testing.T) {
+	model := testModel(numberedLines(20000))
+	model.cursor = 3000 // Line 3001
+	model.viewport = 2990
+
+	model, cmd := typeFilter(model, ".even")
+	if model.filterJob == nil || cmd == nil {
+		t.Fatal("Expected filtering to start in the background")
+	}
+	if len(model.filteredLines) != 0 {
+		t.Errorf("Expected no matches before any are found, got %d", len(model.filteredLines))
+	}
+
+	// One chunk in, the matches found so far are shown
+	newModel, cmd := model.Update(cmd())
+	model = newModel.(Model)
+	if len(model.filteredLines) != filterChunkSize/2 {
+		t.Errorf("Expected %d matches after the first chunk, got %d", filterChunkSize/2, len(model.filteredLines))
+	}
+	if want := fmt.Sprintf("Filtering %d%%", filterChunkSize*100/20000); !strings.Contains(model.View(), want) {
+		t.Error("Expected progress in the status bar")
+	}
+
+	model = runCmd(model, cmd)
+	if len(model.filteredLines) != 10000 {
+		t.Fatalf("Expected 10000 matches, got %d", len(model.filteredLines))
+	}
+	if got := model.cursorLineNumber(); got != 3000 {
+		t.Errorf("Expected the cursor on line 3000, the closest match above 3001, got %d", got)
+	}
+	if strings.Contains(model.View(), "Filtering") {
+		t.Error("Expected the progress to be gone once done")
+	}
+}
+
+// TestFilterCancel tests that Esc stops filtering and restores the previous filters
+func TestFilterCancel(t *testing.T) {
+	model := testModel(numberedLines(20000))
+	model, _ = typeFilter(model, ".line > 100")
+	model = runCmd(model, model.filtering.wait())
+
+	model, cmd := typeFilter(model, ".even")
+	newModel, _ := model.Update(cmd())
+	model = newModel.(Model)
+
+	newModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if cmd != nil {
+		t.Fatal("Esc while filtering should cancel, not quit")
+	}
+	if model.filterJob != nil {
+		t.Fatal("Expected filtering to stop")
+	}
+	if len(model.filters) != 1 || model.filters[0].Expression != ".line > 100" {
+		t.Errorf("Expected the filters from before, got %d filters", len(model.filters))
+	}
+	if len(model.filteredLines) != 19900 {
+		t.Errorf("Expected the earlier 19900 matches back, got %d", len(model.filteredLines))
+	}
+}
+
+// TestFilterReplaced tests that changing the filters again replaces the running job
+func TestFilterReplaced(t *testing.T) {
+	model := testModel(numberedLines(5000))
+	model, first := typeFilter(model, ".even")
+	stale := model.filterJob
+
+	model, cmd := typeFilter(model, ".line <= 10")
+	if model.filterJob == stale {
+		t.Fatal("Expected a new job")
+	}
+	if msg := first(); msg != nil {
+		t.Errorf("Expected the replaced job to stop reporting, got %T", msg)
+	}
+
+	model = runCmd(model, cmd)
+	if len(model.filteredLines) != 5 {
+		t.Errorf("Expected the even lines up to 10, got %d", len(model.filteredLines))
+	}
+
+	// Esc goes back to before both changes
+	model, _ = typeFilter(model, ".even")
+	model, _ = typeFilter(model, ".line == 1")
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if len(model.filters) != 2 || len(model.filteredLines) != 5 {
+		t.Errorf("Expected the 5 matches of two filters back, got %d matches of %d filters", len(model.filteredLines), len(model.filters))
+	}
+}
+
+// TestFilterLinesAddedMeanwhile tests that lines appended while filtering are filtered too
+func TestFilterLinesAddedMeanwhile(t *testing.T) {
+	model := testModel(numberedLines(3000))
+	model, cmd := typeFilter(model, ".even")
+
+	var newLines []LogLine
+	for i := 3001; i <= 3010; i++ {
+		newLines = append(newLines, parseLogLine(i, fmt.Sprintf(`{"line": %d, "even": %v}`, i, i%2 == 0)))
+	}
+	model.appendNewLines(newLines)
+
+	model = runCmd(model, cmd)
+	if len(model.filteredLines) != 1505 {
+		t.Fatalf("Expected 1505 matches, got %d", len(model.filteredLines))
+	}
+	if last := model.filteredLines[len(model.filteredLines)-1].LineNumber; last != 3010 {
+		t.Errorf("Expected the appended lines to be filtered, last match is %d", last)
+	}
+}
+
+// TestFilterAppendedLinesOnly tests that earlier lines aren't checked again when lines are appended
+func TestFilterAppendedLinesOnly(t *testing.T) {
+	model := testModel(numberedLines(100))
+	filter, err := newFilter(".even")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.filters = []Filter{filter}
+	model.applyFilters()
+
+	// Swap the filter without filtering again, so only lines checked
+	// from now on use it
+	odd, err := newFilter(".even | not")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.filters[0] = odd
+
+	var newLines []LogLine
+	for i := 101; i <= 110; i++ {
+		newLines = append(newLines, parseLogLine(i, fmt.Sprintf(`{"line": %d, "even": %v}`, i, i%2 == 0)))
+	}
+	model.appendNewLines(newLines)
+
+	if len(model.filteredLines) != 55 {
+		t.Fatalf("Expected 50 earlier matches and 5 new ones, got %d", len(model.filteredLines))
+	}
+	if model.filteredLines[49].LineNumber != 100 || model.filteredLines[50].LineNumber != 101 {
+		t.Errorf("Expected the earlier matches to be kept as they were, got %d then %d",
+			model.filteredLines[49].LineNumber, model.filteredLines[50].LineNumber)
+	}
+
+	// Chunks loaded to the end are filtered as they arrive
+	newModel, _ := model.Update(loadToEndMsg{newLines: []LogLine{parseLogLine(111, `{"line": 111, "even": false}`)}})
+	model = newModel.(Model)
+	if len(model.filteredLines) != 56 {
+		t.Errorf("Expected the chunk's line to be filtered, got %d matches", len(model.filteredLines))
+	}
+}
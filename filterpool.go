@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/itchyny/gojq"
+)
+
+// filterBatchSize is how many lines a worker checks at a time
+const filterBatchSize = 256
+
+// compileFilters returns the enabled filters, compiled so they can be run
+// on every core without compiling them again
+func compileFilters(filters []Filter) []Filter {
+	var compiled []Filter
+	for _, filter := range filters {
+		if !filter.Enabled {
+			continue
+		}
+		if filter.Code == nil && filter.Query != nil {
+			if code, err := gojq.Compile(filter.Query, gojq.WithVariables(filterVariables)); err == nil {
+				filter.Code = code
+			}
+		}
+		compiled = append(compiled, filter)
+	}
+	return compiled
+}
+
+// filterLines returns the lines that pass every enabled filter
+func filterLines(filters []Filter, invalid invalidLineMode, lines []LogLine) []LogLine {
+	filtered, _ := filterLinesUntil(filters, invalid, lines, nil)
+	return filtered
+}
+
+// filterLinesUntil returns the lines that pass every enabled filter, checking
+// them on every core when there are enough. It gives up, returning false, as
+// soon as cancel is closed.
+func filterLinesUntil(filters []Filter, invalid invalidLineMode, lines []LogLine, cancel <-chan struct{}) ([]LogLine, bool) {
+	filters = compileFilters(filters)
+	batches := (len(lines) + filterBatchSize - 1) / filterBatchSize
+	workers := parseWorkers()
+	if workers > batches {
+		workers = batches
+	}
+	if workers <= 1 {
+		return filterBatch(filters, invalid, lines, cancel)
+	}
+
+	results := make([][]LogLine, batches)
+	var next atomic.Int64
+	var cancelled atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !cancelled.Load() {
+				i := int(next.Add(1)) - 1
+				if i >= batches {
+					return
+				}
+				end := (i + 1) * filterBatchSize
+				if end > len(lines) {
+					end = len(lines)
+				}
+				matches, ok := filterBatch(filters, invalid, lines[i*filterBatchSize:end], cancel)
+				if !ok {
+					cancelled.Store(true)
+					return
+				}
+				results[i] = matches
+			}
+		}()
+	}
+	wg.Wait()
+	if cancelled.Load() {
+		return nil, false
+	}
+
+	var filtered []LogLine
+	for _, matches := range results {
+		filtered = append(filtered, matches...)
+	}
+	return filtered, true
+}
+
+// filterBatch checks lines one after another, stopping when cancel is closed
+func filterBatch(filters []Filter, invalid invalidLineMode, lines []LogLine, cancel <-chan struct{}) ([]LogLine, bool) {
+	var filtered []LogLine
+	for _, line := range lines {
+		select {
+		case <-cancel:
+			return nil, false
+		default:
+		}
+		if linePassesFilters(filters, invalid, line) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered, true
+}
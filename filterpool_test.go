@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/itchyny/gojq"
+)
+
+// poolTestLines returns n lines, every seventh of them not JSON
+func poolTestLines(n int) []LogLine {
+	lines := make([]LogLine, n)
+	for i := range lines {
+		if (i+1)%7 == 0 {
+			lines[i] = parseLogLine(i+1, fmt.Sprintf("plain text line %d", i+1))
+		} else {
+			lines[i] = parseLogLine(i+1, fmt.Sprintf(`{"line": %d, "level": "%s"}`, i+1, []string{"info", "warn", "error"}[i%3]))
+		}
+	}
+	return lines
+}
+
+// TestFilterLinesMatchesSequential tests that filtering on several cores keeps matches in order
+func TestFilterLinesMatchesSequential(t *testing.T) {
+	withParseWorkers(t, 8)
+	lines := poolTestLines(10000)
+
+	filter, err := newFilter(`.level == "error"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := []Filter{filter}
+
+	var want []LogLine
+	for _, line := range lines {
+		if linePassesFilters(filters, invalidLinesFiltered, line) {
+			want = append(want, line)
+		}
+	}
+	got := filterLines(filters, invalidLinesFiltered, lines)
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d matches, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].LineNumber != want[i].LineNumber {
+			t.Fatalf("Match %d is line %d, expected line %d", i, got[i].LineNumber, want[i].LineNumber)
+		}
+	}
+}
+
+// TestFilterLinesCancelled tests that a closed cancel channel stops filtering
+func TestFilterLinesCancelled(t *testing.T) {
+	withParseWorkers(t, 4)
+	filter, err := newFilter(".line > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if matches, ok := filterLinesUntil([]Filter{filter}, invalidLinesFiltered, poolTestLines(5000), cancel); ok || matches != nil {
+		t.Errorf("Expected filtering to stop, got %d matches", len(matches))
+	}
+}
+
+// TestCompileFilters tests that only enabled filters are kept, all compiled
+func TestCompileFilters(t *testing.T) {
+	query, err := gojq.Parse(".line > 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled, err := newFilter(".line > 1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled.Enabled = false
+
+	compiled := compileFilters([]Filter{{Expression: ".line > 10", Query: query, Enabled: true}, disabled})
+	if len(compiled) != 1 || compiled[0].Code == nil {
+		t.Fatalf("Expected one compiled filter, got %+v", compiled)
+	}
+	if got := len(filterLines(compiled, invalidLinesFiltered, poolTestLines(20))); got != 9 {
+		t.Errorf("Expected lines 11 to 20 without line 14, got %d matches", got)
+	}
+}
+
+// benchmarkFilter measures filtering generated lines with the given number of workers
+func benchmarkFilter(b *testing.B, workers int) {
+	withParseWorkers(b, workers)
+	lines := poolTestLines(100000)
+	filter, err := newFilter(`.level | test("err")`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	filters := []Filter{filter}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		filterLines(filters, invalidLinesFiltered, lines)
+	}
+}
+
+// BenchmarkFilterLines measures filtering on every core
+func BenchmarkFilterLines(b *testing.B) {
+	benchmarkFilter(b, runtime.NumCPU())
+}
+
+// BenchmarkFilterLinesSequential measures filtering on one core, as before
+func BenchmarkFilterLinesSequential(b *testing.B) {
+	benchmarkFilter(b, 1)
+}
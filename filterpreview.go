@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// filterPreviewDelay is how long typing must pause before matches are counted
+const filterPreviewDelay = 300 * time.Millisecond
+
+// filterPreview is the feedback on the filter being typed
+type filterPreview struct {
+	seq     int   // Bumped on every change to the input, to spot stale results
+	err     error // Why the expression isn't valid, nil if it is
+	valid   bool
+	counted bool // Whether matches and total are known for the input as it is
+	matches int
+	total   int
+
+	counting backgroundJob // Counts the matches, kept when the input changes
+}
+
+// Message sent once typing has paused
+type filterPreviewTickMsg struct {
+	seq int
+}
+
+// Message with how many lines the filter being typed would match
+type filterPreviewMsg struct {
+	seq     int
+	matches int
+	total   int
+}
+
+// previewFilters returns the filters as they would be with expression added,
+// or in place of the filter being edited, without counting failures
+func (m Model) previewFilters(expression string) ([]Filter, error) {
+	filter, err := newFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := append([]Filter(nil), m.filters...)
+	if m.filterEditMode && m.filterCursor < len(filters) {
+		filter.Enabled = filters[m.filterCursor].Enabled
+		filters[m.filterCursor] = filter
+	} else {
+		filters = append(filters, filter)
+	}
+	for i := range filters {
+		filters[i].failures = nil
+	}
+	return filters, nil
+}
+
+// updateFilterPreview checks the filter being typed after it changed, and
+// counts its matches once typing pauses
+func (m *Model) updateFilterPreview(expression string) tea.Cmd {
+	m.clearFilterPreview()
+	if expression == "" {
+		return nil
+	}
+
+	if _, err := m.previewFilters(expression); err != nil {
+		m.preview.err = err
+		return nil
+	}
+	m.preview.valid = true
+
+	seq := m.preview.seq
+	return tea.Tick(filterPreviewDelay, func(time.Time) tea.Msg {
+		return filterPreviewTickMsg{seq: seq}
+	})
+}
+
+// clearFilterPreview stops any count and forgets the feedback, once the input closes
+func (m *Model) clearFilterPreview() {
+	m.preview.counting.stop()
+	m.preview = filterPreview{seq: m.preview.seq + 1, counting: m.preview.counting}
+}
+
+// startPreviewCount counts the lines in memory that would match in the background
+func (m *Model) startPreviewCount(expression string) tea.Cmd {
+	filters, err := m.previewFilters(expression)
+	if err != nil {
+		return nil
+	}
+
+	lines := m.lines
+	invalid := m.invalidLines
+	return m.preview.counting.start(func(r jobRun) {
+		if matches, ok := filterLinesUntil(filters, invalid, lines, r.cancel); ok {
+			r.send(filterPreviewMsg{seq: r.seq, matches: len(matches), total: len(lines)})
+		}
+	})
+}
+
+// previewInput returns the expression in whichever filter input is open
+func (m Model) previewInput() string {
+	if m.filterEditMode {
+		return m.filterEditInput
+	}
+	return m.filterInput
+}
+
+// handleFilterPreviewTick starts counting if the input hasn't changed since the tick was set
+func (m *Model) handleFilterPreviewTick(msg filterPreviewTickMsg) tea.Cmd {
+	if msg.seq != m.preview.seq || !m.preview.valid || (!m.filterMode && !m.filterEditMode) {
+		return nil
+	}
+	return m.startPreviewCount(m.previewInput())
+}
+
+// handleFilterPreview shows the count, unless the input changed meanwhile
+func (m *Model) handleFilterPreview(msg filterPreviewMsg) {
+	if m.preview.counting.isStale(msg.seq) {
+		return
+	}
+	m.preview.counting.finish()
+	m.preview.counted = true
+	m.preview.matches = msg.matches
+	m.preview.total = msg.total
+	m.enforceMemoryBudget()
+}
+
+// filterInputHint returns what follows the filter being typed: why it
+// doesn't parse, or how many lines it would match
+func (m Model) filterInputHint() string {
+	switch {
+	case m.inputErr != nil:
+		return m.inputErrorText()
+	case m.preview.err != nil:
+		return "  Invalid: " + m.preview.err.Error()
+	case m.preview.counted:
+		return fmt.Sprintf("  %s of %s loaded lines match",
+			humanize.Comma(int64(m.preview.matches)), humanize.Comma(int64(m.preview.total)))
+	case m.preview.counting.running():
+		return "  Counting matches..."
+	}
+	return ""
+}
+
+// filterInputColors returns the background and foreground of the filter
+// input, green or red once what's typed is valid or not
+func (m Model) filterInputColors(background, foreground string) (string, string) {
+	switch {
+	case m.inputErr != nil || m.preview.err != nil:
+		return "#FF6B6B", "#000000"
+	case m.preview.valid:
+		return "#7CCD7C", "#000000"
+	}
+	return background, foreground
+}
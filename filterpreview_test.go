@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeKeys types text one key at a time, returning the command from the last key
+func typeKeys(model Model, text string) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+	for _, r := range text {
+		var newModel tea.Model
+		newModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		model = newModel.(Model)
+	}
+	return model, cmd
+}
+
+// TestFilterPreview tests validating and counting the filter being typed
+func TestFilterPreview(t *testing.T) {
+	model := testModel(numberedLines(5000))
+	model.filters = []Filter{mustFilter(t, ".line <= 100")}
+	model.applyFilters()
+
+	model, _ = typeKeys(model, "f")
+	model, cmd := typeKeys(model, ".even |")
+	if cmd != nil || model.preview.err == nil {
+		t.Fatal("Expected an incomplete expression to be invalid without counting")
+	}
+	if !strings.Contains(model.View(), "Invalid: column 8: unexpected EOF") {
+		t.Error("Expected why it's invalid in the filter input")
+	}
+
+	model, _ = typeKeys(model, " not")
+	if !model.preview.valid || model.preview.err != nil {
+		t.Fatal("Expected a complete expression to be valid")
+	}
+
+	// Typing again before the pause makes the earlier tick stale
+	stale := model.preview.seq
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model = newModel.(Model)
+	model, cmd = typeKeys(model, "t")
+	if cmd == nil {
+		t.Fatal("Expected a tick once typing pauses")
+	}
+	if _, cmd := model.Update(filterPreviewTickMsg{seq: stale}); cmd != nil {
+		t.Error("Expected a stale tick to be ignored")
+	}
+
+	// The count includes the filters already applied
+	newModel, cmd = model.Update(filterPreviewTickMsg{seq: model.preview.seq})
+	model = newModel.(Model)
+	if !strings.Contains(model.View(), "Counting matches") {
+		t.Error("Expected the count to show as running")
+	}
+	model = runCmd(model, cmd)
+	if !strings.Contains(model.View(), "50 of 5,000 loaded lines match") {
+		t.Errorf("Expected the match count in the filter input, got %q", model.filterInputHint())
+	}
+
+	// Closing the input forgets it
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if model.preview.valid || model.preview.counted {
+		t.Error("Expected the preview to be cleared")
+	}
+}
+
+// TestFilterEditPreview tests that editing previews the filter in place of the old one
+func TestFilterEditPreview(t *testing.T) {
+	model := testModel(numberedLines(5000))
+	model.filters = []Filter{mustFilter(t, ".line <= 100"), mustFilter(t, ".even")}
+	model.applyFilters()
+	model.filterManageMode = true
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	model = newModel.(Model)
+	if !model.filterEditMode || !model.preview.valid {
+		t.Fatal("Expected the filter being edited to be checked right away")
+	}
+
+	model, cmd := typeKeys(model, "0")
+	model = runCmd(model, cmd)
+	if !model.preview.counted || model.preview.matches != 500 {
+		t.Errorf("Expected the even lines up to 1000, got %d", model.preview.matches)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// Marker line texts, formatted with the file name
+const (
+	rotatedMarkerFormat   = "--- %s was replaced, following the new file ---"
+	truncatedMarkerFormat = "--- %s was truncated, reading from the start ---"
+)
+
+// defaultPartialLineTimeout is how long an unterminated last line is held back
+// unless -partial-wait is given
+const defaultPartialLineTimeout = 2 * time.Second
+
+// partialLineTimeout is how long an unterminated last line is held back, 0
+// shows it immediately
+var partialLineTimeout = defaultPartialLineTimeout
+
+// followState records how far a followed file has been read
+type followState struct {
+	size         int64       // Bytes read so far
+	info         os.FileInfo // Identity of the file that was read, nil if unknown
+	partialSince time.Time   // When an unterminated last line was first held back, zero if none
+	shown        int64       // Length of an unterminated last line already shown, read again from size
+	finishes     bool        // Whether the first line the last check read replaces the one shown unterminated
+}
+
+// newFollowState returns the state of a file that has been read up to its current size
+func newFollowState(info os.FileInfo) followState {
+	return followState{size: info.Size(), info: info}
+}
+
+// continueFrom makes following carry on where f stopped reading. A last line
+// f read without a newline was shown as it is, but a writer may be in the
+// middle of it, so it's read again from its start to see it finished.
+func (state *followState) continueFrom(f *logFile) {
+	if f.compression != compressionNone {
+		return // Compressed files aren't followed
+	}
+	state.size = f.offset()
+	state.shown = 0
+	state.partialSince = time.Time{}
+	if f.reader.partial {
+		state.size = f.start + f.reader.lineStart
+		state.shown = f.offset() - state.size
+	}
+}
+
+// splitFinished separates the line that finishes one shown unterminated, when
+// a check read one, from the new lines after it, renumbering those to follow
+// on from the lines in memory
+func splitFinished(lines []LogLine, state followState) (finished, rest []LogLine) {
+	if !state.finishes || len(lines) == 0 {
+		return nil, lines
+	}
+	rest = lines[1:]
+	shiftLineNumbers(rest, -1)
+	return lines[:1], rest
+}
+
+// finishLines puts lines that finish ones shown unterminated in their place,
+// which is the last line in memory from the same file
+func (m *Model) finishLines(finished []LogLine) {
+	for _, line := range finished {
+		i := len(m.lines) - 1
+		for i >= 0 && (m.lines[i].Source != line.Source || m.lines[i].Marker) {
+			i--
+		}
+		if i < 0 {
+			continue // Dropped from memory meanwhile
+		}
+		if m.linesShared() {
+			m.lines = slices.Clone(m.lines) // A background job is reading the old ones
+		}
+		line.LineNumber = m.lines[i].LineNumber
+		m.lines[i] = line
+		m.stats.edited = true
+		m.refilterLine(line)
+	}
+}
+
+// refilterLine updates the filtered lines after a line in memory changed
+func (m *Model) refilterLine(line LogLine) {
+	m.contextCache.reset()
+	if m.filterJob != nil {
+		return // Filtered when the job finishes
+	}
+	if len(m.filters) == 0 {
+		m.filteredLines = m.lines
+		return
+	}
+	i, found := slices.BinarySearchFunc(m.filteredLines, line.LineNumber, func(l LogLine, n int) int {
+		return l.LineNumber - n
+	})
+	passes := len(filterLines(m.filters, m.invalidLines, []LogLine{line})) > 0
+	switch {
+	case found && passes:
+		m.filteredLines = slices.Clone(m.filteredLines)
+		m.filteredLines[i] = line
+	case found:
+		m.filteredLines = slices.Delete(slices.Clone(m.filteredLines), i, i+1)
+	case passes:
+		m.filteredLines = slices.Insert(slices.Clone(m.filteredLines), i, line)
+	}
+}
+
+// newMarkerLine creates a line that isn't part of the file, shown to explain a
+// break in what was read
+func newMarkerLine(lineNumber int, text string) LogLine {
+	return LogLine{
+		LineNumber: lineNumber,
+		RawLine:    text,
+		Marker:     true,
+	}
+}
+
+// partialLineWait returns how long until a held back line should be shown
+// anyway, so the file is checked again even if the writer goes quiet. It
+// returns 0 when no line is held back.
+func (m Model) partialLineWait() time.Duration {
+	states := []followState{m.follow}
+	for _, source := range m.sources {
+		states = append(states, source.follow)
+	}
+
+	var wait time.Duration
+	for _, state := range states {
+		if state.partialSince.IsZero() {
+			continue
+		}
+		remaining := time.Until(state.partialSince.Add(partialLineTimeout))
+		if remaining < time.Millisecond {
+			remaining = time.Millisecond
+		}
+		if wait == 0 || remaining < wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// readNewLines reads any lines written to a followed file since the last
+// check, numbering them from firstLineNumber, and returns the updated state.
+// When the file was replaced or truncated it's read from the start after a
+// marker line. A missing file is assumed to be mid-rotation and is simply
+// checked again next time. An unterminated last line isn't returned until
+// it's complete or has waited partialLineTimeout, and once shown it's read
+// again until it's finished, the line that replaces it being returned first
+// with state.finishes set.
+func readNewLines(filename string, state followState, firstLineNumber int) ([]LogLine, followState) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, state
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, state
+	}
+
+	var newLines []LogLine
+	switch {
+	case state.info != nil && !os.SameFile(state.info, stat):
+		newLines = append(newLines, newMarkerLine(firstLineNumber, fmt.Sprintf(rotatedMarkerFormat, filename)))
+		state.size = 0
+		state.shown = 0
+		state.partialSince = time.Time{}
+	case stat.Size() < state.size:
+		newLines = append(newLines, newMarkerLine(firstLineNumber, fmt.Sprintf(truncatedMarkerFormat, filename)))
+		state.size = 0
+		state.shown = 0
+		state.partialSince = time.Time{}
+	}
+	state.info = stat
+
+	if stat.Size() <= state.size+state.shown {
+		state.finishes = false
+		return newLines, state // No new content
+	}
+
+	// Seek to where the last check stopped
+	if _, err := file.Seek(state.size, io.SeekStart); err != nil {
+		return newLines, state
+	}
+
+	// Read new lines, counting what was consumed rather than trusting the
+	// size from Stat since the file may keep growing while it's read
+	reader := newLineReader(file, maxLineSize)
+	var raw []rawLine
+	consumed := int64(0)
+	held := false
+	shown := state.shown
+	state.shown = 0
+	state.finishes = false
+
+	for {
+		offset := reader.offset
+		text, truncated, err := reader.readLine()
+		if err != nil {
+			break
+		}
+
+		if reader.partial {
+			// Hold back half written lines, reading them again from their
+			// start on a later check, and keep one already shown meanwhile
+			if partialLineTimeout > 0 {
+				if state.partialSince.IsZero() {
+					state.partialSince = time.Now()
+				}
+				if time.Since(state.partialSince) < partialLineTimeout {
+					held = true
+					if offset == 0 {
+						state.shown = shown
+					}
+					break
+				}
+			}
+			// Shown as it is, but read again to see it finished
+			state.shown = reader.offset - offset
+		} else {
+			consumed = reader.offset
+		}
+		if offset == 0 && shown > 0 {
+			state.finishes = true
+		}
+
+		raw = append(raw, rawLine{text: text, truncated: truncated, offset: state.size + offset})
+	}
+
+	if !held {
+		state.partialSince = time.Time{}
+	}
+	state.size += consumed
+	return append(newLines, parseRawLines(raw, firstLineNumber+len(newLines))...), state
+}
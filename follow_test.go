@@ -0,0 +1,334 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appendToFile appends data to a file
+func appendToFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// statFollowState returns the state of a file read up to its current size
+func statFollowState(t *testing.T, path string) followState {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newFollowState(info)
+}
+
+// TestReadNewLinesAppended tests reading lines appended since the last check
+func TestReadNewLinesAppended(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(`{"n": 1}`+"\n"))
+	state := statFollowState(t, path)
+
+	lines, state := readNewLines(path, state, 2)
+	if len(lines) != 0 {
+		t.Fatalf("Expected no new lines, got %d", len(lines))
+	}
+
+	appendToFile(t, path, `{"n": 2}`+"\n"+`{"n": 3}`+"\n")
+	lines, state = readNewLines(path, state, 2)
+	if len(lines) != 2 || lines[0].LineNumber != 2 || lines[1].LineNumber != 3 {
+		t.Fatalf("Expected lines 2 and 3, got %+v", lines)
+	}
+	for _, line := range lines {
+		if line.Marker {
+			t.Error("Appended lines shouldn't be markers")
+		}
+	}
+
+	info, _ := os.Stat(path)
+	if state.size != info.Size() {
+		t.Errorf("Expected state size %d, got %d", info.Size(), state.size)
+	}
+}
+
+// TestReadNewLinesTruncated tests following a file truncated in place
+func TestReadNewLinesTruncated(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(generateLogContent(10)))
+	state := statFollowState(t, path)
+
+	// copytruncate empties the file, then the application writes again
+	if err := os.WriteFile(path, []byte(`{"after": "truncate"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, state := readNewLines(path, state, 11)
+	if len(lines) != 2 {
+		t.Fatalf("Expected a marker and one line, got %d lines", len(lines))
+	}
+	if !lines[0].Marker || !strings.Contains(lines[0].RawLine, "truncated") {
+		t.Errorf("Expected a truncation marker, got %+v", lines[0])
+	}
+	if !lines[1].IsValid || lines[1].LineNumber != 12 {
+		t.Errorf("Expected valid line 12 after the marker, got %+v", lines[1])
+	}
+	if state.size != int64(len(`{"after": "truncate"}`+"\n")) {
+		t.Errorf("Expected the new file to be read to its end, got size %d", state.size)
+	}
+}
+
+// TestReadNewLinesRotated tests following a file that was moved away and recreated
+func TestReadNewLinesRotated(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(`{"n": 1}`+"\n"))
+	state := statFollowState(t, path)
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new file doesn't exist yet, keep waiting
+	lines, state := readNewLines(path, state, 2)
+	if len(lines) != 0 {
+		t.Fatalf("Expected no lines while the file is missing, got %d", len(lines))
+	}
+
+	// The new file is larger than the old one, so only its identity shows the rotation
+	if err := os.WriteFile(path, []byte(generateLogContent(3)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, _ = readNewLines(path, state, 2)
+	if len(lines) != 4 {
+		t.Fatalf("Expected a marker and three lines, got %d lines", len(lines))
+	}
+	if !lines[0].Marker || !strings.Contains(lines[0].RawLine, "replaced") {
+		t.Errorf("Expected a rotation marker, got %+v", lines[0])
+	}
+	if !strings.Contains(lines[1].RawLine, `"line": 1,`) {
+		t.Errorf("Expected the new file to be read from the start, got %q", lines[1].RawLine)
+	}
+}
+
+// TestFollowMsgUpdatesModel tests that tailing continues from the new state
+func TestFollowMsgUpdatesModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	filter, err := newFilter(`.level == "error"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := Model{
+		filename:    path,
+		lines:       []LogLine{parseLogLine(1, `{"level": "error"}`)},
+		filters:     []Filter{filter},
+		lastLineNum: 1,
+		height:      10,
+		width:       80,
+	}
+	model.applyFilters()
+
+	state := followState{size: 42}
+	msg := followMsg{
+		newLines: []LogLine{
+			newMarkerLine(2, "--- app.log was truncated, reading from the start ---"),
+			parseLogLine(3, `{"level": "info"}`),
+		},
+		states:          []followState{state},
+		firstLineNumber: 2,
+	}
+
+	newModel, _ := model.Update(msg)
+	model = newModel.(Model)
+
+	if model.follow.size != 42 {
+		t.Errorf("Expected follow state to be updated, got size %d", model.follow.size)
+	}
+	if model.lastLineNum != 3 {
+		t.Errorf("Expected last line number 3, got %d", model.lastLineNum)
+	}
+
+	// Markers stay visible through filters
+	visible := model.getVisibleLines()
+	if len(visible) != 2 || !visible[1].Marker {
+		t.Fatalf("Expected the error line and the marker to be visible, got %+v", visible)
+	}
+
+	view := model.View()
+	if !strings.Contains(view, "was truncated") {
+		t.Error("Marker should be shown in the view")
+	}
+	if strings.Contains(view, "[INVALID JSON]") {
+		t.Error("Markers shouldn't be flagged as invalid JSON")
+	}
+}
+
+// setPartialLineTimeout overrides partialLineTimeout for the duration of a test
+func setPartialLineTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	previous := partialLineTimeout
+	partialLineTimeout = timeout
+	t.Cleanup(func() { partialLineTimeout = previous })
+}
+
+// TestReadNewLinesHoldsBackPartialLine tests that half written lines wait for their newline
+func TestReadNewLinesHoldsBackPartialLine(t *testing.T) {
+	setPartialLineTimeout(t, time.Hour)
+
+	path := writeTestFile(t, "app.log", nil)
+	state := statFollowState(t, path)
+
+	appendToFile(t, path, `{"n": 1}`+"\n"+`{"n": `)
+	lines, state := readNewLines(path, state, 1)
+	if len(lines) != 1 || !lines[0].IsValid {
+		t.Fatalf("Expected only the complete line, got %+v", lines)
+	}
+	if state.size != int64(len(`{"n": 1}`+"\n")) {
+		t.Errorf("Partial line shouldn't count as read, got size %d", state.size)
+	}
+	if state.partialSince.IsZero() {
+		t.Error("Expected the partial line to be held back")
+	}
+
+	model := Model{follow: state}
+	if wait := model.partialLineWait(); wait <= 0 || wait > time.Hour {
+		t.Errorf("Expected to check again within the timeout, got %v", wait)
+	}
+
+	appendToFile(t, path, "2}\n")
+	lines, state = readNewLines(path, state, 2)
+	if len(lines) != 1 || !lines[0].IsValid || lines[0].RawLine != `{"n": 2}` {
+		t.Fatalf("Expected the completed line as one valid line, got %+v", lines)
+	}
+	if !state.partialSince.IsZero() {
+		t.Error("Nothing should be held back once the line is complete")
+	}
+}
+
+// TestReadNewLinesPartialLineTimeout tests that a line that's never finished is shown eventually
+func TestReadNewLinesPartialLineTimeout(t *testing.T) {
+	setPartialLineTimeout(t, 10*time.Millisecond)
+
+	path := writeTestFile(t, "app.log", nil)
+	state := statFollowState(t, path)
+
+	appendToFile(t, path, `{"n": `)
+	lines, state := readNewLines(path, state, 1)
+	if len(lines) != 0 {
+		t.Fatalf("Expected the partial line to be held back, got %+v", lines)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	lines, state = readNewLines(path, state, 1)
+	if len(lines) != 1 || lines[0].RawLine != `{"n": ` {
+		t.Fatalf("Expected the partial line after the timeout, got %+v", lines)
+	}
+	if state.size != 0 || state.shown != int64(len(`{"n": `)) || !state.partialSince.IsZero() {
+		t.Errorf("Expected the partial line shown but read again, got %+v", state)
+	}
+
+	if wait := (Model{follow: state}).partialLineWait(); wait != 0 {
+		t.Errorf("Expected no wait without a held back line, got %v", wait)
+	}
+
+	// Finishing it replaces the line shown
+	appendToFile(t, path, "1}\n")
+	lines, state = readNewLines(path, state, 2)
+	if len(lines) != 1 || lines[0].RawLine != `{"n": 1}` || !state.finishes {
+		t.Errorf("Expected the finished line in place of the partial one, got %+v", lines)
+	}
+}
+
+// TestReadNewLinesPartialLineNoWait tests that a zero timeout shows partial lines immediately
+func TestReadNewLinesPartialLineNoWait(t *testing.T) {
+	setPartialLineTimeout(t, 0)
+
+	path := writeTestFile(t, "app.log", nil)
+	state := statFollowState(t, path)
+
+	appendToFile(t, path, `{"n": `)
+	lines, _ := readNewLines(path, state, 1)
+	if len(lines) != 1 {
+		t.Fatalf("Expected the partial line immediately, got %d lines", len(lines))
+	}
+}
+
+// TestFollowingFinishesLoadedPartialLine tests that a last line loaded
+// without its newline is shown, and put right once the writer finishes it
+func TestFollowingFinishesLoadedPartialLine(t *testing.T) {
+	setPartialLineTimeout(t, time.Hour)
+	complete := `{"n": 1}` + "\n"
+
+	loaders := map[string]func(t *testing.T, path string) Model{
+		"lazy loading": func(t *testing.T, path string) Model {
+			lines, file, err := loadInitialChunk(path, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := Model{filename: path, lines: lines, filteredLines: lines, lastLineNum: len(lines), file: file, follow: statFollowState(t, path)}
+			if err := model.loadMoreLines(lazyLoadChunkSize); err != nil {
+				t.Fatal(err)
+			}
+			return model
+		},
+		"tail window": func(t *testing.T, path string) Model {
+			lines, _, tail, err := loadTailWindow(path, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tail.Close()
+			model := Model{filename: path, lines: lines, filteredLines: lines, lastLineNum: len(lines), isFileFullyLoaded: true, follow: statFollowState(t, path)}
+			model.follow.continueFrom(tail)
+			return model
+		},
+		"merged": func(t *testing.T, path string) Model {
+			other := writeTestFile(t, "other.log", nil)
+			sources := newLogSources([]string{path, other})
+			lines, err := loadMergedLines(sources, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return Model{lines: lines, filteredLines: lines, lastLineNum: len(lines), isFileFullyLoaded: true, sources: sources}
+		},
+	}
+	check := func(model Model) Model {
+		cmd := checkForNewLines(model.filename, model.follow, model.lastLineNum)
+		if len(model.sources) > 0 {
+			cmd = checkSourcesForNewLines(model.sources, nil, model.lastLineNum)
+		}
+		newModel, _ := model.Update(cmd())
+		return newModel.(Model)
+	}
+
+	for name, load := range loaders {
+		t.Run(name, func(t *testing.T) {
+			path := writeTestFile(t, "app.log", []byte(complete+`{"n": `))
+			model := load(t, path)
+			if !model.isFileFullyLoaded || len(model.lines) != 2 || model.lines[1].RawLine != `{"n": ` {
+				t.Fatalf("Expected the unterminated line shown as it is, got %+v", model.lines)
+			}
+
+			// Shown already, so it isn't read again until it changes
+			if model = check(model); len(model.lines) != 2 {
+				t.Fatalf("Expected nothing new, got %+v", model.lines)
+			}
+
+			appendToFile(t, path, "2}\n"+`{"n": 3}`+"\n")
+			model = check(model)
+			if len(model.lines) != 3 || !model.lines[1].IsValid || model.lines[1].RawLine != `{"n": 2}` || model.lines[1].LineNumber != 2 {
+				t.Fatalf("Expected the line finished in place, got %+v", model.lines)
+			}
+			if model.lines[2].RawLine != `{"n": 3}` || model.lastLineNum != 3 {
+				t.Errorf("Expected the next line after it, got %+v", model.lines[2])
+			}
+			if len(model.filteredLines) != 3 || model.filteredLines[1].RawLine != `{"n": 2}` {
+				t.Errorf("Expected the shown lines updated, got %+v", model.filteredLines)
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ require (
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/dustin/go-humanize v1.0.1
 	github.com/itchyny/gojq v0.12.17
+	github.com/klauspost/compress v1.18.0
 	golang.design/x/clipboard v0.7.1
+	golang.org/x/sys v0.33.0
 )
 
 require (
@@ -33,6 +35,5 @@ require (
 	golang.org/x/image v0.28.0 // indirect
 	golang.org/x/mobile v0.0.0-20250606033058-a2a15c67f36f // indirect
 	golang.org/x/sync v0.15.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 )
@@ -0,0 +1,69 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// parseLines parses raw lines, numbered from 1
+func parseLines(rawLines ...string) []LogLine {
+	lines := make([]LogLine, len(rawLines))
+	for i, raw := range rawLines {
+		lines[i] = parseLogLine(i+1, raw)
+	}
+	return lines
+}
+
+// testModel returns a model with lines fully loaded from app.log and all of
+// them shown, on a screen 120 wide and 20 high
+func testModel(lines []LogLine) Model {
+	model := Model{
+		filename:          "app.log",
+		lines:             lines,
+		filteredLines:     lines,
+		isFileFullyLoaded: true,
+		height:            20,
+		width:             120,
+	}
+	if len(lines) > 0 {
+		model.lastLineNum = lines[len(lines)-1].LineNumber
+	}
+	return model
+}
+
+// runCmd runs a command and feeds its messages back to the model, then the
+// commands those return, until there are none left. Spinner ticks are
+// dropped, as the spinner would otherwise never stop.
+func runCmd(model Model, cmd tea.Cmd) Model {
+	pending := []tea.Cmd{cmd}
+	for len(pending) > 0 {
+		msgs := runBatch(pending[0])
+		pending = pending[1:]
+		for _, msg := range msgs {
+			if _, ok := msg.(spinnerTickMsg); ok || msg == nil {
+				continue
+			}
+			newModel, next := model.Update(msg)
+			model = newModel.(Model)
+			if next != nil {
+				pending = append(pending, next)
+			}
+		}
+	}
+	return model
+}
+
+// runBatch runs a command, flattening batches, and returns the messages
+func runBatch(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runBatch(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileInode returns 0 where inode numbers aren't available, so sidecar
+// indexes are matched by size and modification time only
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
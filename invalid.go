@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// invalidLineMode is how filters treat lines that aren't JSON
+type invalidLineMode int
+
+const (
+	invalidLinesFiltered invalidLineMode = iota // Filtered like any other line, by their raw text
+	invalidLinesShown                           // Always shown
+	invalidLinesHidden                          // Never shown while filtering
+)
+
+// invalidLineModes are the names of the modes, as given to -invalid and
+// shown in the status bar
+var invalidLineModes = []string{"filter", "show", "hide"}
+
+func (mode invalidLineMode) String() string {
+	return invalidLineModes[mode]
+}
+
+// parseInvalidLineMode returns the mode with the given name
+func parseInvalidLineMode(name string) (invalidLineMode, error) {
+	for i, mode := range invalidLineModes {
+		if name == mode {
+			return invalidLineMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("must be one of filter, show or hide")
+}
+
+// invalidLineData is what filters see for a line that isn't JSON
+func invalidLineData(line LogLine) interface{} {
+	return map[string]interface{}{
+		"_raw":     line.RawLine,
+		"_invalid": true,
+	}
+}
+
+// cycleInvalidLines switches to the next way of treating invalid lines and
+// filters again. Cancelling goes back to the previous one.
+func (m *Model) cycleInvalidLines() tea.Cmd {
+	previous := filterState{
+		filters:  append([]Filter(nil), m.filters...),
+		invalid:  m.invalidLines,
+		lines:    m.lines,
+		filtered: m.filteredLines,
+	}
+	m.invalidLines = (m.invalidLines + 1) % invalidLineMode(len(invalidLineModes))
+	if len(m.filters) == 0 {
+		return nil // Every line is shown without filters
+	}
+	return m.refilterFrom(previous)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicLines returns JSON lines mixed with a plain text panic
+func panicLines() []LogLine {
+	return parseLines(
+		`{"level": "info", "msg": "starting"}`,
+		`{"level": "error", "msg": "about to panic"}`,
+		`panic: runtime error: index out of range`,
+		`goroutine 1 [running]:`,
+		`{"level": "info", "msg": "restarted"}`,
+	)
+}
+
+// visibleLineNumbers returns the numbers of the lines shown
+func visibleLineNumbers(model Model) []int {
+	var numbers []int
+	for _, line := range model.getVisibleLines() {
+		numbers = append(numbers, line.LineNumber)
+	}
+	return numbers
+}
+
+// TestFilterInvalidLines tests that lines that aren't JSON are filtered by their raw text
+func TestFilterInvalidLines(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []int
+	}{
+		{`.level == "error"`, []int{2}},
+		{`$raw | test("panic")`, []int{2, 3}},
+		{`._invalid`, []int{3, 4}},
+		{`._invalid and (._raw | startswith("goroutine"))`, []int{4}},
+		{`.level | ascii_downcase == "info"`, []int{1, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			model := testModel(panicLines())
+			model, cmd := typeFilter(model, tt.expression)
+			model = runCmd(model, cmd)
+			if got := visibleLineNumbers(model); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected lines %v, got %v", tt.want, got)
+			}
+			if count := model.filterErrorCount(); count != 0 {
+				t.Errorf("Expected failures on invalid lines not to be counted, got %d", count)
+			}
+		})
+	}
+}
+
+// TestCycleInvalidLines tests switching between showing, hiding and filtering invalid lines
+func TestCycleInvalidLines(t *testing.T) {
+	model := testModel(panicLines())
+	model, cmd := typeFilter(model, `.level == "error"`)
+	model = runCmd(model, cmd)
+	if !strings.Contains(model.View(), "I=filter") {
+		t.Error("Expected the mode in the status bar")
+	}
+
+	model, cmd = pressKey(model, "i")
+	model = runCmd(model, cmd)
+	if got := visibleLineNumbers(model); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf("Expected the invalid lines shown, got %v", got)
+	}
+	if !strings.Contains(model.View(), "I=show") {
+		t.Error("Expected the new mode in the status bar")
+	}
+
+	model, cmd = typeFilter(model, `$raw | test("panic")`)
+	model = runCmd(model, cmd)
+	model, cmd = pressKey(model, "i")
+	model = runCmd(model, cmd)
+	if got := visibleLineNumbers(model); !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected the invalid lines hidden, got %v", got)
+	}
+
+	// Esc goes back to the previous mode
+	model, _ = pressKey(model, "i")
+	if model.filterJob == nil {
+		t.Fatal("Expected filtering in the background")
+	}
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if model.invalidLines != invalidLinesHidden {
+		t.Errorf("Expected invalid lines hidden again, got %v", model.invalidLines)
+	}
+	if got := visibleLineNumbers(model); !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected the earlier result back, got %v", got)
+	}
+}
+
+// TestParseInvalidLineMode tests the names given to -invalid
+func TestParseInvalidLineMode(t *testing.T) {
+	for _, mode := range []invalidLineMode{invalidLinesFiltered, invalidLinesShown, invalidLinesHidden} {
+		parsed, err := parseInvalidLineMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("Expected %v back, got %v (%v)", mode, parsed, err)
+		}
+	}
+	if _, err := parseInvalidLineMode("sometimes"); err == nil {
+		t.Error("Expected an unknown mode to fail")
+	}
+}
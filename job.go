@@ -0,0 +1,104 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// backgroundJob runs work on a goroutine, one run at a time. A run can be
+// stopped and waited for, and its results told apart from those of runs
+// started before it.
+type backgroundJob struct {
+	seq     int           // Bumped whenever a run starts or is stopped, to spot stale results
+	cancel  chan struct{} // Closed to stop the run, nil when none is running
+	done    chan struct{} // Closed once the run's goroutine has returned
+	results chan tea.Msg  // Closed along with done
+}
+
+// jobRun is what the goroutine of a run reports its results with
+type jobRun struct {
+	seq     int             // Carried by the results, for isStale
+	cancel  <-chan struct{} // Closed when the run is stopped
+	results chan<- tea.Msg
+}
+
+// send hands a result to the model, or returns false when the run was
+// stopped before it was taken
+func (r jobRun) send(msg tea.Msg) bool {
+	select {
+	case r.results <- msg:
+		return true
+	case <-r.cancel:
+		return false
+	}
+}
+
+// stopped reports whether the run was stopped
+func (r jobRun) stopped() bool {
+	select {
+	case <-r.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// start stops any run in progress and runs work on a goroutine, returning
+// the command that waits for its first result
+func (j *backgroundJob) start(work func(r jobRun)) tea.Cmd {
+	j.stop()
+	j.seq++
+	j.cancel = make(chan struct{})
+	j.done = make(chan struct{})
+	j.results = make(chan tea.Msg)
+
+	r := jobRun{seq: j.seq, cancel: j.cancel, results: j.results}
+	done := j.done
+	go func() {
+		defer close(done)
+		defer close(r.results)
+		work(r)
+	}()
+	return j.wait()
+}
+
+// wait returns the command that waits for the next result of the run in
+// progress, which gives nil once the run is stopped or has returned
+func (j *backgroundJob) wait() tea.Cmd {
+	results := j.results
+	return func() tea.Msg {
+		return <-results
+	}
+}
+
+// stop stops the run in progress, waiting until its goroutine has returned.
+// Results it sent before are stale from then on.
+func (j *backgroundJob) stop() {
+	if j.cancel == nil {
+		return
+	}
+	close(j.cancel)
+	<-j.done
+	j.seq++
+	j.cancel, j.done, j.results = nil, nil, nil
+}
+
+// finish waits for the run in progress to return once its last result was
+// taken, after which it's no longer running
+func (j *backgroundJob) finish() {
+	if j.cancel == nil {
+		return
+	}
+	<-j.done
+	j.cancel, j.done, j.results = nil, nil, nil
+}
+
+// running reports whether a run is in progress
+func (j backgroundJob) running() bool {
+	return j.cancel != nil
+}
+
+// isStale reports whether a result with seq is from a run that was stopped
+// or replaced since
+func (j backgroundJob) isStale(seq int) bool {
+	return seq != j.seq
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestBackgroundJob tests running, stopping and replacing runs
+func TestBackgroundJob(t *testing.T) {
+	var job backgroundJob
+	cmd := job.start(func(r jobRun) {
+		r.send(r.seq)
+	})
+	if !job.running() {
+		t.Fatal("Expected the job to be running")
+	}
+	seq := cmd().(int)
+	if job.isStale(seq) {
+		t.Error("Expected the result of the latest run to be current")
+	}
+	job.finish()
+	if job.running() {
+		t.Error("Expected the job to be done once finished")
+	}
+
+	// A stopped run sends nothing more, and what it sent before is stale
+	started := make(chan struct{})
+	cmd = job.start(func(r jobRun) {
+		close(started)
+		<-r.cancel
+		if r.send(r.seq) || !r.stopped() {
+			t.Error("Expected nothing to be sent once stopped")
+		}
+	})
+	<-started
+	seq = job.seq
+	job.stop()
+	if job.running() || !job.isStale(seq) {
+		t.Error("Expected the stopped run's results to be stale")
+	}
+	if msg := cmd(); msg != nil {
+		t.Errorf("Expected nothing from a stopped run, got %v", msg)
+	}
+
+	// Starting again replaces the run in progress
+	job.start(func(r jobRun) { <-r.cancel })
+	seq = job.seq
+	job.start(func(r jobRun) {})
+	if !job.isStale(seq) {
+		t.Error("Expected the replaced run's results to be stale")
+	}
+	job.stop()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// decodeJSONLine parses a raw line as a single JSON value of any kind, with
+// its numbers exact
+func decodeJSONLine(rawLine string) (interface{}, bool) {
+	// Numbers are kept as written until it's known whether they have a
+	// fraction or exponent, which is lost once they're floats
+	decoder := json.NewDecoder(strings.NewReader(rawLine))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, false // More than one value, or text after it
+	}
+	return exactNumbers(value), true
+}
+
+// exactNumbers replaces the numbers decoded with UseNumber with ints, or big
+// ints when they're too large, and floats only when they have a fraction or
+// exponent, the same way jq filters see them
+func exactNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return exactNumber(v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = exactNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = exactNumbers(item)
+		}
+	}
+	return value
+}
+
+// exactNumber converts a number as written into the closest value filters can use
+func exactNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil && i >= math.MinInt && i <= math.MaxInt {
+		return int(i)
+	}
+	if !strings.ContainsAny(n.String(), ".eE") {
+		if i, ok := new(big.Int).SetString(n.String(), 10); ok {
+			return i
+		}
+	}
+	f, _ := n.Float64() // Out of range floats are infinite, as in jq
+	return f
+}
+
+// indentJSON pretty prints a raw JSON line as written, in its own key order
+func indentJSON(rawLine string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(rawLine)), "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
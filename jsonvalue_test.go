@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// ansiPattern matches the escape codes syntax highlighting adds
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// stripANSI removes colors from rendered text
+func stripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
+
+// TestDecodeJSONLine tests which lines are JSON and what they decode to
+func TestDecodeJSONLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawLine string
+		valid   bool
+	}{
+		{"object", `{"level": "info"}`, true},
+		{"array", `["2024-01-15T10:30:00Z", "error", "boom"]`, true},
+		{"string", `"just a message"`, true},
+		{"number", `42`, true},
+		{"boolean", `true`, true},
+		{"padded", `  {"a": 1}  `, true},
+		{"plain text", `panic: boom`, false},
+		{"trailing text", `200 OK`, false},
+		{"two values", `{"a": 1} {"b": 2}`, false},
+		{"empty", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := parseLogLine(1, tt.rawLine)
+			if line.IsValid != tt.valid {
+				t.Errorf("Expected valid=%v for %q", tt.valid, tt.rawLine)
+			}
+		})
+	}
+}
+
+// TestExactNumbers tests that numbers keep every digit
+func TestExactNumbers(t *testing.T) {
+	line := parseLogLine(1, `{"id": 1234567890123456789, "huge": 123456789012345678901234567890, "ratio": 0.25, "count": 3, "big": 1e400}`)
+	data := line.data().(map[string]interface{})
+
+	if id, ok := data["id"].(int); !ok || id != 1234567890123456789 {
+		t.Errorf("Expected the exact id, got %v (%T)", data["id"], data["id"])
+	}
+	if huge, ok := data["huge"].(*big.Int); !ok || huge.String() != "123456789012345678901234567890" {
+		t.Errorf("Expected a big int, got %v (%T)", data["huge"], data["huge"])
+	}
+	if ratio, ok := data["ratio"].(float64); !ok || ratio != 0.25 {
+		t.Errorf("Expected a float, got %v (%T)", data["ratio"], data["ratio"])
+	}
+	if count, ok := data["count"].(int); !ok || count != 3 {
+		t.Errorf("Expected an int, got %v (%T)", data["count"], data["count"])
+	}
+	if f, ok := data["big"].(float64); !ok || !math.IsInf(f, 1) {
+		t.Errorf("Expected a float too large to be infinite, got %v (%T)", data["big"], data["big"])
+	}
+
+	small := parseLogLine(2, `[1, 2.5, {"n": 7}]`).data().([]interface{})
+	if _, ok := small[0].(int); !ok {
+		t.Errorf("Expected an int, got %T", small[0])
+	}
+	if n, ok := small[2].(map[string]interface{})["n"].(int); !ok || n != 7 {
+		t.Errorf("Expected a nested int, got %v", small[2])
+	}
+
+	// Whole numbers written with a fraction or exponent stay floats
+	written := parseLogLine(3, `[1.0, 1e2, -0.0]`).data().([]interface{})
+	for i, want := range []float64{1, 100, 0} {
+		if f, ok := written[i].(float64); !ok || f != want {
+			t.Errorf("Expected the float %v, got %v (%T)", want, written[i], written[i])
+		}
+	}
+}
+
+// TestExactNumberFiltersAndViews tests that filters and views see exact numbers
+func TestExactNumberFiltersAndViews(t *testing.T) {
+	model := testModel(parseLines(
+		`{"id": 1234567890123456789}`,
+		`{"id": 1234567890123456788}`,
+	))
+	model.filters = []Filter{mustFilter(t, ".id == 1234567890123456789")}
+	model.applyFilters()
+	if len(model.filteredLines) != 1 || model.filteredLines[0].LineNumber != 1 {
+		t.Fatalf("Expected only line 1 to match the exact id, got %d lines", len(model.filteredLines))
+	}
+
+	query, err := parseViewExpression(".id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.viewFilter = query
+	if got := model.applyViewTransform(model.lines[1].data()); got != "1234567890123456788" {
+		t.Errorf("Expected the exact id in the view, got %s", got)
+	}
+}
+
+// TestNonObjectLines tests that arrays and other values are filtered, viewed and pretty printed
+func TestNonObjectLines(t *testing.T) {
+	lines := parseLines(
+		`["2024-01-15T10:30:00Z", "info", "started"]`,
+		`["2024-01-15T10:30:01Z", "error", "failed"]`,
+		`"a bare string"`,
+	)
+	model := testModel(lines)
+	if strings.Contains(model.View(), "[INVALID JSON]") {
+		t.Error("Expected arrays and strings to be valid lines")
+	}
+
+	model.filters = []Filter{mustFilter(t, `.[1] == "error"`)}
+	model.applyFilters()
+	if len(model.filteredLines) != 1 || model.filteredLines[0].LineNumber != 2 {
+		t.Errorf("Expected the error line, got %d lines", len(model.filteredLines))
+	}
+
+	query, err := parseViewExpression(`"\(.[1]): \(.[2])"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.viewFilter = query
+	if !strings.Contains(model.View(), "error: failed") {
+		t.Error("Expected the view to apply to arrays")
+	}
+
+	model.selectedLine = &lines[0]
+	model.showPretty = true
+	if view := model.View(); !strings.Contains(view, `"info"`) || strings.Contains(view, "Error formatting") {
+		t.Errorf("Expected the array pretty printed, got %s", view)
+	}
+}
+
+// TestPrettyKeepsKeyOrder tests that the pretty view shows keys and numbers as written
+func TestPrettyKeepsKeyOrder(t *testing.T) {
+	line := parseLogLine(1, `{"timestamp": "2024-01-15T10:30:00Z", "level": "info", "msg": "hi", "id": 1234567890123456789, "ratio": 1.50, "rate": 1e2}`)
+	model := testModel([]LogLine{line})
+	model.selectedLine = &line
+	model.showPretty = true
+
+	allLines, _ := model.prettyLines()
+	plain := stripANSI(strings.Join(allLines, "\n"))
+	order := []string{`"timestamp"`, `"level"`, `"msg"`, `"id": 1234567890123456789`, `"ratio": 1.50`, `"rate": 1e2`}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(plain, want)
+		if i < 0 {
+			t.Fatalf("Expected %s in the pretty view, got:\n%s", want, plain)
+		}
+		if i < last {
+			t.Errorf("Expected %s after the keys before it", want)
+		}
+		last = i
+	}
+}
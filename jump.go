@@ -0,0 +1,199 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prevLoadThreshold is how close to the top of the window the cursor gets
+// before the lines above it are loaded
+const prevLoadThreshold = 100
+
+// jumpChunkSize is how many lines are loaded after the line jumped to
+const jumpChunkSize = 1000
+
+// lastLine asks goToLine for the last line of the file, wherever that is
+const lastLine = -1
+
+// Message with the window of lines loaded around a line reached through the
+// index, or read backwards from the end of the file
+type jumpMsg struct {
+	newLines []LogLine
+	file     *logFile // Positioned after the last line, for lazy loading
+	atEOF    bool
+	target   int  // Line to put the cursor on, or lastLine
+	approx   bool // Whether the line numbers are estimated
+	err      error
+}
+
+// Message with the lines just before the first line in memory
+type prevLinesMsg struct {
+	newLines  []LogLine
+	before    int64 // Offset of the line they were read back from
+	fromStart bool  // Whether the lines start the file, which makes their numbers exact
+	err       error
+}
+
+// jumpToLineCmd loads the lines from the indexed block containing target
+// onwards, or the last block through to the end of the file for lastLine
+func jumpToLineCmd(filename string, index *lineIndex, target int) tea.Cmd {
+	return func() tea.Msg {
+		lineNumber := target
+		if target == lastLine {
+			lineNumber = index.lines // Block holding the last indexed line
+		}
+		offset, first := index.lineOffset(lineNumber)
+
+		file, err := openLogFileAt(filename, offset)
+		if err != nil {
+			return jumpMsg{target: target, err: err}
+		}
+
+		// Going to the end reads everything after the last indexed line,
+		// including lines appended since the index was built
+		maxLines := 0
+		if target != lastLine {
+			maxLines = target - first + jumpChunkSize
+		}
+
+		lines, atEOF, err := file.readLines(first, maxLines)
+		if err != nil {
+			file.Close()
+			return jumpMsg{target: target, err: err}
+		}
+		return jumpMsg{newLines: lines, file: file, atEOF: atEOF, target: target}
+	}
+}
+
+// reopenLogFile opens a file positioned at the start of lineNumber, seeking to
+// the closest indexed line when there's an index and reading from the start
+// otherwise
+func reopenLogFile(filename string, index *lineIndex, lineNumber int) (*logFile, error) {
+	var file *logFile
+	var err error
+	skip := lineNumber - 1
+	if index != nil {
+		offset, first := index.lineOffset(lineNumber)
+		file, err = openLogFileAt(filename, offset)
+		skip = lineNumber - first
+	} else {
+		file, err = openLogFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.skipLines(skip); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// hasLinesAbove reports whether the lines in memory start after the first line of the file
+func (m Model) hasLinesAbove() bool {
+	return len(m.lines) > 0 && (m.lines[0].LineNumber > 1 || m.approxLineNumbers)
+}
+
+// canJump reports whether lines outside the window can be reached through the index
+func (m Model) canJump() bool {
+	return m.index != nil && !m.approxLineNumbers && m.canSeek()
+}
+
+// loadPrevLinesIfNeeded returns a command loading the lines above the window
+// when the cursor is close to its top
+func (m *Model) loadPrevLinesIfNeeded() tea.Cmd {
+	if !m.hasLinesAbove() || !m.canSeek() || m.loadingPrevLines || m.cursor > prevLoadThreshold {
+		return nil
+	}
+	m.loadingPrevLines = true
+	return loadPrevLinesCmd(m.filename, m.lines[0])
+}
+
+// goToLine moves the cursor to a line, or to the end for lastLine, jumping
+// through the index when it isn't in memory. Jumps asked for while the index
+// is being built or other lines are loading happen once they're done.
+func (m *Model) goToLine(lineNumber int) tea.Cmd {
+	if lineNumber < 1 && lineNumber != lastLine {
+		lineNumber = 1
+	}
+
+	if m.loadingMoreLines || m.loadingPrevLines || m.showSpinner {
+		m.pendingGoto = lineNumber
+		return nil
+	}
+
+	if lineNumber == lastLine {
+		if m.isFileFullyLoaded {
+			m.moveCursorToEnd()
+			return nil
+		}
+	} else if len(m.lines) > 0 && lineNumber >= m.lines[0].LineNumber &&
+		(lineNumber <= m.lastLineNum || m.isFileFullyLoaded) {
+		m.restorePositionAfterFilter(lineNumber)
+		return nil
+	}
+
+	switch {
+	case m.canJump():
+		m.showSpinner = true
+		m.spinnerFrame = 0
+		return tea.Batch(spinnerTickCmd(), jumpToLineCmd(m.filename, m.index, lineNumber))
+	case lineNumber == lastLine && m.canSeek():
+		// Read the end backwards rather than wait for the index
+		m.showSpinner = true
+		m.spinnerFrame = 0
+		return tea.Batch(spinnerTickCmd(), tailWindowCmd(m.filename))
+	case m.indexing || (m.approxLineNumbers && m.index != nil):
+		// Line numbers can only be found once the index is built and the
+		// lines in memory have been numbered exactly
+		m.pendingGoto = lineNumber
+		return nil
+	case !m.isFileFullyLoaded:
+		// Files that can't be indexed are read up to the line
+		if lineNumber != lastLine {
+			m.pendingGoto = lineNumber
+		}
+		m.showSpinner = true
+		m.spinnerFrame = 0
+		return tea.Batch(spinnerTickCmd(), loadToEndCmd(m.filename, m.file, m.index, m.lastLineNum))
+	}
+
+	m.restorePositionAfterFilter(lineNumber)
+	return nil
+}
+
+// moveCursorToEnd puts the cursor on the last visible line, at the bottom of the screen
+func (m *Model) moveCursorToEnd() {
+	visibleLines := m.getVisibleLines()
+	if len(visibleLines) == 0 {
+		return
+	}
+	m.cursor = len(visibleLines) - 1
+	if m.cursor >= m.height-1 { // Account for status bar only
+		m.viewport = m.cursor - m.height + 2
+	} else {
+		m.viewport = 0
+	}
+	m.lineScrollOffset = 0
+}
+
+// closeLoadedFile closes the lazy loading handle once it has reached the end
+// of the file, and makes tailing continue from where it stopped reading
+func (m *Model) closeLoadedFile() {
+	if m.file == nil {
+		return
+	}
+	m.follow.continueFrom(m.file)
+	m.file.Close()
+	m.file = nil
+}
+
+// resumePendingGoto retries a go to line that had to wait for a load or the index
+func (m *Model) resumePendingGoto() tea.Cmd {
+	if m.pendingGoto == 0 {
+		return nil
+	}
+	lineNumber := m.pendingGoto
+	m.pendingGoto = 0
+	return m.goToLine(lineNumber)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openIndexed opens a generated file in the model the way main does, with
+// the first chunk loaded and the rest left for lazy loading
+func openIndexed(t *testing.T, model Model, lineCount int) Model {
+	t.Helper()
+	path := writeTestFile(t, "big.log", []byte(generateLogContent(lineCount)))
+
+	file, err := openLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, _, err := file.readLines(1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	idx, err := loadLineIndex(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model.filename = path
+	model.lines = lines
+	model.filteredLines = lines
+	model.lastLineNum = lines[len(lines)-1].LineNumber
+	model.isFileFullyLoaded = false
+	model.file = file
+	model.index = idx
+	return model
+}
+
+// TestJumpToLine tests going to a line far past what's loaded
+func TestJumpToLine(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+
+	cmd := model.goToLine(3500)
+	if !model.showSpinner {
+		t.Error("Expected the spinner while jumping")
+	}
+	model = runCmd(model, cmd)
+
+	visible := model.getVisibleLines()
+	if got := visible[model.cursor].LineNumber; got != 3500 {
+		t.Fatalf("Expected the cursor on line 3500, got %d", got)
+	}
+	if model.lines[0].LineNumber == 1 {
+		t.Error("Lines before the indexed block shouldn't be loaded")
+	}
+	if model.isFileFullyLoaded || model.file == nil {
+		t.Error("Expected lazy loading to continue after the window")
+	}
+	if !strings.Contains(model.lines[model.cursor].RawLine, `"line": 3500,`) {
+		t.Errorf("Line numbers don't match the content: %q", model.lines[model.cursor].RawLine)
+	}
+}
+
+// TestJumpToEnd tests going to the end without reading the whole file
+func TestJumpToEnd(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+
+	model = runCmd(model, model.goToLine(lastLine))
+
+	if !model.isFileFullyLoaded || model.file != nil {
+		t.Error("Expected the file to be read to its end and closed")
+	}
+	if model.lastLineNum != 5000 {
+		t.Errorf("Expected last line 5000, got %d", model.lastLineNum)
+	}
+	visible := model.getVisibleLines()
+	if visible[model.cursor].LineNumber != 5000 {
+		t.Errorf("Expected the cursor on the last line, got %d", visible[model.cursor].LineNumber)
+	}
+	if model.follow.size == 0 {
+		t.Error("Tailing should continue from the end of the file")
+	}
+	if !strings.Contains(model.View(), "5,000") {
+		t.Error("Expected the status bar to show the total line count")
+	}
+}
+
+// TestPrevLinesLoaded tests that scrolling to the top of a window loads the block above
+func TestPrevLinesLoaded(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+	model = runCmd(model, model.goToLine(3500))
+
+	first := model.lines[0].LineNumber
+
+	model.cursor = 0
+	model.viewport = 0
+	cmd := model.loadPrevLinesIfNeeded()
+	if cmd == nil {
+		t.Fatal("Expected the lines above the window to be loaded")
+	}
+	newModel, _ := model.Update(cmd())
+	model = newModel.(Model)
+
+	if model.lines[0].LineNumber != first-indexInterval {
+		t.Errorf("Expected the window to start at %d, got %d", first-indexInterval, model.lines[0].LineNumber)
+	}
+	if got := model.getVisibleLines()[model.cursor].LineNumber; got != first {
+		t.Errorf("Expected the cursor to stay on line %d, got %d", first, got)
+	}
+}
+
+// TestGoToLineWaitsForIndex tests that a jump asked for while indexing happens once it's done
+func TestGoToLineWaitsForIndex(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+	idx := model.index
+	model.index = nil
+	model.indexing = true
+
+	if cmd := model.goToLine(4200); cmd != nil {
+		t.Error("Jump should wait for the index")
+	}
+	if model.pendingGoto != 4200 {
+		t.Fatalf("Expected the jump to be pending, got %d", model.pendingGoto)
+	}
+
+	newModel, cmd := model.Update(lineIndexMsg{index: idx})
+	model = runCmd(newModel.(Model), cmd)
+
+	if model.pendingGoto != 0 {
+		t.Error("Pending jump should be cleared")
+	}
+	if got := model.getVisibleLines()[model.cursor].LineNumber; got != 4200 {
+		t.Errorf("Expected the cursor on line 4200, got %d", got)
+	}
+}
+
+// TestGoToLineKey tests typing a line number after ':'
+func TestGoToLineKey(t *testing.T) {
+	model := Model{
+		lines:             []LogLine{parseLogLine(1, `{}`), parseLogLine(2, `{}`), parseLogLine(3, `{}`)},
+		lastLineNum:       3,
+		isFileFullyLoaded: true,
+		height:            10,
+		width:             80,
+	}
+	model.filteredLines = model.lines
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{':'}},
+		{Type: tea.KeyRunes, Runes: []rune{'3'}},
+	} {
+		newModel, _ := model.Update(key)
+		model = newModel.(Model)
+	}
+	if !model.gotoMode || !strings.Contains(model.View(), "Go to line: 3") {
+		t.Fatal("Expected the go to line prompt")
+	}
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.gotoMode || model.cursor != 2 {
+		t.Errorf("Expected the cursor on line 3, got cursor %d", model.cursor)
+	}
+}
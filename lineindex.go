@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// indexInterval is the number of lines between indexed offsets
+const indexInterval = 1000
+
+// indexCacheSuffix is appended to the log file name to name its sidecar index
+const indexCacheSuffix = ".siftidx"
+
+// indexCacheVersion changes whenever the sidecar format does
+const indexCacheVersion = 1
+
+// Message sent when the line index has been built or loaded
+type lineIndexMsg struct {
+	index *lineIndex
+	err   error
+}
+
+// lineIndex maps line numbers to byte offsets in a file
+type lineIndex struct {
+	interval int     // Lines between indexed offsets
+	offsets  []int64 // offsets[k] is where line k*interval+1 starts
+	lines    int     // Complete lines in the indexed part of the file
+	size     int64   // Bytes indexed, up to the end of the last complete line
+}
+
+// indexCacheFile is the sidecar file format
+type indexCacheFile struct {
+	Version  int
+	Inode    uint64
+	Size     int64
+	ModTime  int64
+	Interval int
+	Lines    int
+	Indexed  int64
+	Offsets  []int64
+}
+
+// buildLineIndex scans r for newlines, recording the offset of every interval-th line
+func buildLineIndex(r io.Reader, interval int) (*lineIndex, error) {
+	idx := &lineIndex{
+		interval: interval,
+		offsets:  []int64{0},
+	}
+
+	buf := make([]byte, 1024*1024)
+	var pos int64
+	for {
+		n, err := r.Read(buf)
+		chunk := buf[:n]
+		for {
+			i := bytes.IndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			idx.lines++
+			idx.size = pos + int64(i) + 1
+			if idx.lines%interval == 0 {
+				idx.offsets = append(idx.offsets, idx.size)
+			}
+			pos += int64(i) + 1
+			chunk = chunk[i+1:]
+		}
+		pos += int64(len(chunk))
+
+		if err == io.EOF {
+			return idx, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// lineOffset returns the offset of the closest indexed line at or before
+// lineNumber, and that line's number
+func (idx *lineIndex) lineOffset(lineNumber int) (int64, int) {
+	k := (lineNumber - 1) / idx.interval
+	if k >= len(idx.offsets) {
+		k = len(idx.offsets) - 1
+	}
+	if k < 0 {
+		k = 0
+	}
+	return idx.offsets[k], k*idx.interval + 1
+}
+
+// loadLineIndex returns the index of a file, from its sidecar when useCache
+// is set and the sidecar matches the file, building it otherwise
+func loadLineIndex(filename string, useCache bool) (*lineIndex, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		if idx, ok := readIndexCache(filename, info); ok {
+			return idx, nil
+		}
+	}
+
+	// Only index what was there when the file was stat'ed, so the sidecar
+	// key describes exactly what was indexed
+	idx, err := buildLineIndex(io.LimitReader(file, info.Size()), indexInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		// The cache is an optimization, a read-only directory just means
+		// the file is scanned again next time
+		_ = writeIndexCache(filename, info, idx)
+	}
+	return idx, nil
+}
+
+// loadLineIndexCmd builds or loads the line index in the background
+func loadLineIndexCmd(filename string, useCache bool) tea.Cmd {
+	return func() tea.Msg {
+		idx, err := loadLineIndex(filename, useCache)
+		return lineIndexMsg{index: idx, err: err}
+	}
+}
+
+// readIndexCache loads the sidecar index of a file if it was built for the
+// file as it is now
+func readIndexCache(filename string, info os.FileInfo) (*lineIndex, bool) {
+	f, err := os.Open(filename + indexCacheSuffix)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var cached indexCacheFile
+	if err := gob.NewDecoder(f).Decode(&cached); err != nil {
+		return nil, false
+	}
+
+	if cached.Version != indexCacheVersion || cached.Inode != fileInode(info) ||
+		cached.Size != info.Size() || cached.ModTime != info.ModTime().UnixNano() ||
+		cached.Interval <= 0 || len(cached.Offsets) == 0 {
+		return nil, false
+	}
+
+	return &lineIndex{
+		interval: cached.Interval,
+		offsets:  cached.Offsets,
+		lines:    cached.Lines,
+		size:     cached.Indexed,
+	}, true
+}
+
+// writeIndexCache saves the index of a file to its sidecar
+func writeIndexCache(filename string, info os.FileInfo, idx *lineIndex) error {
+	f, err := os.Create(filename + indexCacheSuffix)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(f).Encode(indexCacheFile{
+		Version:  indexCacheVersion,
+		Inode:    fileInode(info),
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Interval: idx.interval,
+		Lines:    idx.lines,
+		Indexed:  idx.size,
+		Offsets:  idx.offsets,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuildLineIndex tests that indexed offsets point at the right lines
+func TestBuildLineIndex(t *testing.T) {
+	content := generateLogContent(25)
+	idx, err := buildLineIndex(strings.NewReader(content), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idx.lines != 25 {
+		t.Errorf("Expected 25 lines, got %d", idx.lines)
+	}
+	if idx.size != int64(len(content)) {
+		t.Errorf("Expected %d bytes indexed, got %d", len(content), idx.size)
+	}
+	if len(idx.offsets) != 3 {
+		t.Fatalf("Expected offsets for lines 1, 11 and 21, got %v", idx.offsets)
+	}
+	for k, offset := range idx.offsets {
+		want := `{"line": ` + []string{"1,", "11,", "21,"}[k]
+		if !strings.HasPrefix(content[offset:], want) {
+			t.Errorf("Offset %d points at %q, expected line starting with %q", k, content[offset:offset+12], want)
+		}
+	}
+}
+
+// TestBuildLineIndexPartialLine tests that an unterminated last line isn't counted
+func TestBuildLineIndexPartialLine(t *testing.T) {
+	idx, err := buildLineIndex(strings.NewReader("a\nb\nc"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.lines != 2 || idx.size != 4 {
+		t.Errorf("Expected 2 complete lines in 4 bytes, got %d lines in %d bytes", idx.lines, idx.size)
+	}
+}
+
+// TestLineOffset tests finding the closest indexed line
+func TestLineOffset(t *testing.T) {
+	idx := &lineIndex{interval: 10, offsets: []int64{0, 100, 200}, lines: 25}
+
+	tests := []struct {
+		lineNumber int
+		offset     int64
+		first      int
+	}{
+		{1, 0, 1},
+		{10, 0, 1},
+		{11, 100, 11},
+		{25, 200, 21},
+		{1000, 200, 21}, // Past the index, read on from the last block
+		{0, 0, 1},
+	}
+	for _, tt := range tests {
+		offset, first := idx.lineOffset(tt.lineNumber)
+		if offset != tt.offset || first != tt.first {
+			t.Errorf("lineOffset(%d) = %d, %d, expected %d, %d", tt.lineNumber, offset, first, tt.offset, tt.first)
+		}
+	}
+}
+
+// TestLineIndexCache tests saving and reusing the sidecar index
+func TestLineIndexCache(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(generateLogContent(2500)))
+
+	idx, err := loadLineIndex(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + indexCacheSuffix); !os.IsNotExist(err) {
+		t.Error("No sidecar should be written without -index-cache")
+	}
+
+	cached, err := loadLineIndex(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + indexCacheSuffix); err != nil {
+		t.Fatalf("Expected a sidecar to be written: %v", err)
+	}
+
+	info, _ := os.Stat(path)
+	fromCache, ok := readIndexCache(path, info)
+	if !ok {
+		t.Fatal("Expected the sidecar to match the unchanged file")
+	}
+	if fromCache.lines != idx.lines || len(fromCache.offsets) != len(idx.offsets) || cached.lines != idx.lines {
+		t.Errorf("Expected the cached index to match the built one, got %+v", fromCache)
+	}
+
+	// Appending invalidates the sidecar
+	appendToFile(t, path, `{"line": 2501}`+"\n")
+	info, _ = os.Stat(path)
+	if _, ok := readIndexCache(path, info); ok {
+		t.Error("Sidecar shouldn't be used once the file has changed")
+	}
+
+	// And so does touching it, even at the same size
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := loadLineIndex(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.lines != 2501 {
+		t.Errorf("Expected the index to be rebuilt with 2501 lines, got %d", rebuilt.lines)
+	}
+	info, _ = os.Stat(path)
+	if _, ok := readIndexCache(path, info); !ok {
+		t.Error("Expected the rebuilt index to be saved")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// defaultMaxLineSize is the longest line kept in full unless -max-line-size is given
+const defaultMaxLineSize = 1024 * 1024
+
+// maxLineSize is the longest line kept in full, 0 means unlimited
+var maxLineSize = defaultMaxLineSize
+
+// lineReader reads newline terminated lines of arbitrary length
+type lineReader struct {
+	r           *bufio.Reader
+	maxLineSize int   // Longest line kept in full, 0 means unlimited
+	offset      int64 // Bytes consumed so far, including line endings
+	lineStart   int64 // Where the last line read starts
+	partial     bool  // Whether the last line read ended at EOF without a newline
+}
+
+// newLineReader creates a line reader that keeps at most maxLineSize bytes per line
+func newLineReader(r io.Reader, maxLineSize int) *lineReader {
+	return &lineReader{
+		r:           bufio.NewReader(r),
+		maxLineSize: maxLineSize,
+	}
+}
+
+// readLine returns the next line without its line ending. Lines longer than
+// maxLineSize are cut short and reported as truncated, with the rest of the
+// line skipped. A final line without a newline is returned as is, and io.EOF
+// is returned once there are no more lines.
+func (lr *lineReader) readLine() (string, bool, error) {
+	var line []byte
+	truncated := false
+	start := lr.offset
+
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.offset += int64(len(chunk))
+
+		content := chunk
+		if err == nil {
+			content = chunk[:len(chunk)-1] // Drop the newline
+		}
+
+		// Keep what fits, skip the rest of an oversized line
+		if !truncated {
+			if lr.maxLineSize > 0 && len(line)+len(content) > lr.maxLineSize {
+				content = content[:lr.maxLineSize-len(line)]
+				truncated = true
+			}
+			line = append(line, content...)
+		}
+
+		switch err {
+		case nil:
+			if !truncated {
+				line = bytes.TrimSuffix(line, []byte{'\r'})
+			}
+			lr.lineStart, lr.partial = start, false
+			return string(line), truncated, nil
+		case bufio.ErrBufferFull:
+			continue // Line is longer than the buffer, keep reading
+		case io.EOF:
+			if len(line) == 0 && !truncated && len(chunk) == 0 {
+				return "", false, io.EOF
+			}
+			lr.lineStart, lr.partial = start, true
+			return string(line), truncated, nil
+		default:
+			return "", false, err
+		}
+	}
+}
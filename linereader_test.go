@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dustin/go-humanize"
+)
+
+// readAllLines reads every line from a lineReader
+func readAllLines(t *testing.T, lr *lineReader) ([]string, []bool) {
+	t.Helper()
+
+	var lines []string
+	var truncated []bool
+	for {
+		line, wasTruncated, err := lr.readLine()
+		if err == io.EOF {
+			return lines, truncated
+		}
+		if err != nil {
+			t.Fatalf("readLine failed: %v", err)
+		}
+		lines = append(lines, line)
+		truncated = append(truncated, wasTruncated)
+	}
+}
+
+// TestLineReader tests splitting input into lines
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"simple lines", "a\nb\nc\n", []string{"a", "b", "c"}},
+		{"no trailing newline", "a\nb", []string{"a", "b"}},
+		{"CRLF endings", "a\r\nb\r\n", []string{"a", "b"}},
+		{"empty lines", "a\n\nb\n", []string{"a", "", "b"}},
+		{"empty input", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := newLineReader(strings.NewReader(tt.input), 0)
+			lines, _ := readAllLines(t, lr)
+			if strings.Join(lines, "|") != strings.Join(tt.expected, "|") || len(lines) != len(tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, lines)
+			}
+			if lr.offset != int64(len(tt.input)) {
+				t.Errorf("Expected offset %d, got %d", len(tt.input), lr.offset)
+			}
+		})
+	}
+}
+
+// TestLineReaderPartial tests detecting a final line without a newline
+func TestLineReaderPartial(t *testing.T) {
+	lr := newLineReader(strings.NewReader("a\nb"), 0)
+
+	if _, _, err := lr.readLine(); err != nil || lr.partial {
+		t.Errorf("Terminated line shouldn't be partial (err %v)", err)
+	}
+	if _, _, err := lr.readLine(); err != nil || !lr.partial {
+		t.Errorf("Final line without a newline should be partial (err %v)", err)
+	}
+}
+
+// TestLineReaderLongLines tests lines longer than bufio.Scanner's 64 KiB limit
+func TestLineReaderLongLines(t *testing.T) {
+	long := `{"body": "` + strings.Repeat("x", 100*1024) + `"}`
+	input := long + "\n" + `{"after": true}` + "\n"
+
+	lr := newLineReader(strings.NewReader(input), defaultMaxLineSize)
+	lines, truncated := readAllLines(t, lr)
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0] != long || truncated[0] {
+		t.Errorf("Long line should be read in full, got %d bytes", len(lines[0]))
+	}
+	if lines[1] != `{"after": true}` {
+		t.Errorf("Line after the long line was lost, got %q", lines[1])
+	}
+}
+
+// TestLineReaderTruncation tests truncating lines over the maximum size
+func TestLineReaderTruncation(t *testing.T) {
+	input := strings.Repeat("a", 10000) + "\nshort\n" + strings.Repeat("b", 20)
+
+	lr := newLineReader(strings.NewReader(input), 16)
+	lines, truncated := readAllLines(t, lr)
+
+	expected := []string{strings.Repeat("a", 16), "short", strings.Repeat("b", 16)}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+	if !truncated[0] || truncated[1] || !truncated[2] {
+		t.Errorf("Unexpected truncation flags %v", truncated)
+	}
+	if lr.offset != int64(len(input)) {
+		t.Errorf("Truncated lines should still be consumed in full, offset %d of %d", lr.offset, len(input))
+	}
+}
+
+// TestReadLogLinesTruncated tests that truncated lines are marked
+func TestReadLogLinesTruncated(t *testing.T) {
+	lr := newLineReader(strings.NewReader(`{"body": "`+strings.Repeat("x", 100)+`"}`+"\n"), 32)
+	lines, _, err := lr.readLogLines(7, 1)
+	if err != nil || len(lines) != 1 {
+		t.Fatalf("readLogLines failed: %v", err)
+	}
+	line := lines[0]
+	if !line.Truncated || line.IsValid || line.LineNumber != 7 {
+		t.Errorf("Expected truncated invalid line 7, got %+v", line)
+	}
+
+	model := Model{
+		lines:             []LogLine{line},
+		isFileFullyLoaded: true,
+		height:            5,
+		width:             80,
+	}
+	if view := model.View(); !strings.Contains(view, "[TRUNCATED]") {
+		t.Error("Truncated lines should be marked in the view")
+	}
+
+	model.selectedLine = &model.lines[0]
+	model.showPretty = true
+	if view := model.View(); !strings.Contains(view, "Line truncated") {
+		t.Error("Pretty view should explain that the line was truncated")
+	}
+}
+
+// TestLoadAllLinesWithLongLine tests that a long line doesn't drop the rest of the file
+func TestLoadAllLinesWithLongLine(t *testing.T) {
+	content := `{"first": 1}` + "\n" + `{"body": "` + strings.Repeat("x", 200*1024) + `"}` + "\n" + `{"last": 3}` + "\n"
+	path := writeTestFile(t, "app.log", []byte(content))
+
+	lines, err := loadAllLines(path)
+	if err != nil {
+		t.Fatalf("loadAllLines failed: %v", err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !line.IsValid {
+			t.Errorf("Line %d should be valid JSON", line.LineNumber)
+		}
+	}
+}
+
+// TestDefaultMaxLineSizeFlag tests that the flag default round trips through humanize
+func TestDefaultMaxLineSizeFlag(t *testing.T) {
+	size, err := humanize.ParseBytes(humanize.IBytes(defaultMaxLineSize))
+	if err != nil {
+		t.Fatalf("Failed to parse default max line size: %v", err)
+	}
+	if size != defaultMaxLineSize {
+		t.Errorf("Expected %d, got %d", defaultMaxLineSize, size)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionType identifies how a log file is compressed
+type compressionType int
+
+const (
+	compressionNone compressionType = iota
+	compressionGzip
+	compressionZstd
+	compressionBzip2
+)
+
+// Magic bytes at the start of each supported compression format
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// String returns the name shown in the status bar
+func (c compressionType) String() string {
+	switch c {
+	case compressionGzip:
+		return "gzip"
+	case compressionZstd:
+		return "zstd"
+	case compressionBzip2:
+		return "bzip2"
+	}
+	return "none"
+}
+
+// detectCompression identifies the compression format from the start of a file
+func detectCompression(header []byte) compressionType {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return compressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return compressionZstd
+	case bytes.HasPrefix(header, bzip2Magic):
+		return compressionBzip2
+	}
+	return compressionNone
+}
+
+// detectFileCompression identifies the compression format of a file on disk
+func detectFileCompression(filename string) (compressionType, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return compressionNone, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return compressionNone, err
+	}
+	return detectCompression(header[:n]), nil
+}
+
+// decompress wraps r with the decompressor matching its magic bytes. The
+// returned close function releases the decompressor but not r itself.
+func decompress(r io.Reader) (io.Reader, compressionType, func(), error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(len(zstdMagic))
+
+	noop := func() {}
+	switch compression := detectCompression(header); compression {
+	case compressionGzip:
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, compression, noop, err
+		}
+		return gz, compression, func() { gz.Close() }, nil
+	case compressionZstd:
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, compression, noop, err
+		}
+		return zr, compression, zr.Close, nil
+	case compressionBzip2:
+		return bzip2.NewReader(buffered), compression, noop, nil
+	default:
+		return buffered, compression, noop, nil
+	}
+}
+
+// logFile is an open log file read sequentially line by line
+type logFile struct {
+	file         *os.File
+	reader       *lineReader
+	compression  compressionType
+	closeDecoder func()
+	start        int64 // Offset reading started from, always 0 for compressed files
+}
+
+// openLogFile opens a log file for reading, decompressing it if needed
+func openLogFile(filename string) (*logFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r, compression, closeDecoder, err := decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &logFile{
+		file:         file,
+		reader:       newLineReader(r, maxLineSize),
+		compression:  compression,
+		closeDecoder: closeDecoder,
+	}, nil
+}
+
+// openLogFileAt opens an uncompressed log file for reading from offset, which
+// must be the start of a line
+func openLogFileAt(filename string, offset int64) (*logFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &logFile{
+		file:         file,
+		reader:       newLineReader(file, maxLineSize),
+		compression:  compressionNone,
+		closeDecoder: func() {},
+		start:        offset,
+	}, nil
+}
+
+// offset returns where the next line starts, which for a compressed file is
+// an offset into the decompressed content
+func (f *logFile) offset() int64 {
+	return f.start + f.reader.offset
+}
+
+// readLines reads up to maxLines lines, or every remaining line when maxLines
+// is 0, numbering them from firstLineNumber. atEOF reports whether the end of
+// the file was reached.
+func (f *logFile) readLines(firstLineNumber, maxLines int) (lines []LogLine, atEOF bool, err error) {
+	lines, atEOF, err = f.reader.readLogLines(firstLineNumber, maxLines)
+	for i := range lines {
+		lines[i].Offset += f.start
+	}
+	return lines, atEOF, err
+}
+
+// skipLines discards the next n lines without parsing them
+func (f *logFile) skipLines(n int) error {
+	for i := 0; i < n; i++ {
+		if _, _, err := f.reader.readLine(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the decompressor and the underlying file
+func (f *logFile) Close() error {
+	f.closeDecoder()
+	return f.file.Close()
+}
+
+// uncompressedSize returns the size of a log file's content once
+// decompressed. ok is false when the format doesn't record it (bzip2, or zstd
+// written without a content size), in which case progress can't be estimated.
+func uncompressedSize(filename string) (size int64, ok bool, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+
+	header := make([]byte, zstd.HeaderMaxSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, false, err
+	}
+	header = header[:n]
+
+	switch detectCompression(header) {
+	case compressionNone:
+		return stat.Size(), true, nil
+	case compressionGzip:
+		// The gzip trailer ends with the uncompressed size modulo 2^32. It only
+		// covers the last member and wraps for files over 4 GiB, so it's only
+		// trusted when it's plausible for the compressed size.
+		if stat.Size() < 18 {
+			return 0, false, nil
+		}
+		trailer := make([]byte, 4)
+		if _, err := file.ReadAt(trailer, stat.Size()-4); err != nil {
+			return 0, false, err
+		}
+		isize := int64(binary.LittleEndian.Uint32(trailer))
+		if isize < stat.Size()/2 {
+			return 0, false, nil
+		}
+		return isize, true, nil
+	case compressionZstd:
+		var h zstd.Header
+		if err := h.Decode(header); err != nil || !h.HasFCS {
+			return 0, false, nil
+		}
+		return int64(h.FrameContentSize), true, nil
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// bzip2 of two JSON lines, there is no bzip2 writer in the standard library
+const bzip2Fixture = "QlpoOTFBWSZTWVmDndUAABxZgAAQEAQAEAOnnYogAFCmTEyDIwSqbI1G1GJvUR9j0k8EXGJ7k6zsa4/48FAjWlXNqRsqwkOcF3JFOFCQWYOd1Q=="
+
+// generateLogContent returns n JSON log lines
+func generateLogContent(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `{"line": %d, "msg": "message number %d"}`+"\n", i, i)
+	}
+	return b.String()
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zstdBytes compresses data with zstd
+func zstdBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	return w.EncodeAll([]byte(data), nil)
+}
+
+// writeTestFile writes data to a file in a temporary directory
+func writeTestFile(t testing.TB, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestDetectCompression tests magic byte detection
+func TestDetectCompression(t *testing.T) {
+	bz2, _ := base64.StdEncoding.DecodeString(bzip2Fixture)
+
+	tests := []struct {
+		name     string
+		header   []byte
+		expected compressionType
+	}{
+		{"gzip", gzipBytes(t, "x"), compressionGzip},
+		{"zstd", zstdBytes(t, "x"), compressionZstd},
+		{"bzip2", bz2, compressionBzip2},
+		{"plain JSON", []byte(`{"level": "info"}`), compressionNone},
+		{"empty", nil, compressionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCompression(tt.header); got != tt.expected {
+				t.Errorf("detectCompression() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestOpenCompressedLogFile tests reading lines from compressed files
+func TestOpenCompressedLogFile(t *testing.T) {
+	content := generateLogContent(2)
+	bz2, err := base64.StdEncoding.DecodeString(bzip2Fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected compressionType
+	}{
+		{"plain", []byte(content), compressionNone},
+		{"gzip", gzipBytes(t, content), compressionGzip},
+		{"zstd", zstdBytes(t, content), compressionZstd},
+		{"bzip2", bz2, compressionBzip2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "app.log", tt.data)
+
+			file, err := openLogFile(path)
+			if err != nil {
+				t.Fatalf("openLogFile failed: %v", err)
+			}
+			defer file.Close()
+
+			if file.compression != tt.expected {
+				t.Errorf("Expected compression %v, got %v", tt.expected, file.compression)
+			}
+
+			lines, atEOF, err := file.readLines(1, 0)
+			if err != nil {
+				t.Fatalf("readLines failed: %v", err)
+			}
+			if !atEOF {
+				t.Error("Reading all lines should reach EOF")
+			}
+			if len(lines) != 2 {
+				t.Fatalf("Expected 2 lines, got %d", len(lines))
+			}
+			for _, line := range lines {
+				if !line.IsValid {
+					t.Errorf("Line %d should be valid JSON: %q", line.LineNumber, line.RawLine)
+				}
+			}
+		})
+	}
+}
+
+// TestCompressedLazyLoading tests reading a compressed file in chunks
+func TestCompressedLazyLoading(t *testing.T) {
+	path := writeTestFile(t, "app.log.gz", gzipBytes(t, generateLogContent(25)))
+
+	lines, file, err := loadInitialChunk(path, 10)
+	if err != nil {
+		t.Fatalf("loadInitialChunk failed: %v", err)
+	}
+	if len(lines) != 10 {
+		t.Fatalf("Expected 10 lines in the initial chunk, got %d", len(lines))
+	}
+
+	model := Model{lines: lines, file: file}
+	for !model.isFileFullyLoaded {
+		msg := loadMoreLinesCmd(model.file, len(model.lines)+1)()
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+
+	if len(model.lines) != 25 {
+		t.Fatalf("Expected 25 lines after lazy loading, got %d", len(model.lines))
+	}
+	for i, line := range model.lines {
+		if line.LineNumber != i+1 {
+			t.Errorf("Expected line number %d, got %d", i+1, line.LineNumber)
+		}
+		if !strings.Contains(line.RawLine, fmt.Sprintf(`"line": %d,`, i+1)) {
+			t.Errorf("Line %d has unexpected content %q", i+1, line.RawLine)
+		}
+	}
+	if model.file != nil {
+		t.Error("File handle should be closed once fully loaded")
+	}
+}
+
+// TestUncompressedSize tests size detection used for progress estimation
+func TestUncompressedSize(t *testing.T) {
+	content := generateLogContent(50)
+	bz2, _ := base64.StdEncoding.DecodeString(bzip2Fixture)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		size   int64
+		wantOK bool
+	}{
+		{"plain", []byte(content), int64(len(content)), true},
+		{"gzip", gzipBytes(t, content), int64(len(content)), true},
+		{"zstd", zstdBytes(t, content), int64(len(content)), true},
+		{"bzip2", bz2, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "app.log", tt.data)
+			size, ok, err := uncompressedSize(path)
+			if err != nil {
+				t.Fatalf("uncompressedSize failed: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("Expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if ok && size != tt.size {
+				t.Errorf("Expected size %d, got %d", tt.size, size)
+			}
+		})
+	}
+}
+
+// TestEstimateTotalLinesCompressed tests line estimation for compressed files
+func TestEstimateTotalLinesCompressed(t *testing.T) {
+	content := generateLogContent(1000)
+
+	gzPath := writeTestFile(t, "app.log.gz", gzipBytes(t, content))
+	estimate, err := estimateTotalLines(gzPath, 100)
+	if err != nil {
+		t.Fatalf("estimateTotalLines failed: %v", err)
+	}
+	if estimate < 900 || estimate > 1100 {
+		t.Errorf("Expected an estimate near 1000 lines for gzip, got %d", estimate)
+	}
+
+	// Formats without a recorded size give no estimate rather than a wrong one
+	bz2, _ := base64.StdEncoding.DecodeString(bzip2Fixture)
+	bz2Path := writeTestFile(t, "app.log.bz2", bz2)
+	estimate, err = estimateTotalLines(bz2Path, 100)
+	if err != nil {
+		t.Fatalf("estimateTotalLines failed: %v", err)
+	}
+	if estimate != 0 {
+		t.Errorf("Expected no estimate for bzip2, got %d", estimate)
+	}
+}
+
+// TestCompressedFileNotTailed tests that compressed files aren't polled for new lines
+func TestCompressedFileNotTailed(t *testing.T) {
+	model := Model{compression: compressionGzip, filename: "app.log.gz"}
+	_, cmd := model.Update(fileEventMsg{})
+	if cmd != nil {
+		t.Error("Compressed files should not be polled for new lines")
+	}
+}
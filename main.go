@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bufio"
 	_ "embed"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,11 +33,20 @@ var (
 				Foreground(lipgloss.Color("#666666")).
 				Padding(0, 1)
 
+	markerLineStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFAA00")).
+			Bold(true).
+			Padding(0, 1)
+
 	selectedLineStyle = lipgloss.NewStyle().
 				Background(lipgloss.Color("#004499")).
 				Foreground(lipgloss.Color("#FFFFFF")).
 				Padding(0, 1)
 
+	filterErrorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF5555")).
+				Padding(0, 1)
+
 	statusStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#0066CC")).
 			Foreground(lipgloss.Color("#FFFFFF")).
@@ -47,16 +56,26 @@ var (
 // Messages for file watching
 type newLinesMsg []LogLine
 
-type tickMsg time.Time
+// Message for file tailing, with how far each followed file has now been read
+type followMsg struct {
+	newLines        []LogLine
+	finished        []LogLine     // Lines shown unterminated, now finished, to put in their place
+	states          []followState // The followed file, or one per merged source
+	firstLineNumber int           // Number given to the first new line, to spot checks made stale by a jump
+}
 
 // Message for lazy loading
 type loadMoreLinesMsg struct {
-	err error
+	newLines   []LogLine
+	file       *logFile // Handle reopened after lines were dropped, nil otherwise
+	err        error
+	isComplete bool
 }
 
 // Message for loading to end
 type loadToEndMsg struct {
 	newLines   []LogLine
+	file       *logFile // Handle to keep reading from
 	err        error
 	isComplete bool
 }
@@ -73,15 +92,30 @@ type operationCompleteMsg struct {
 type Filter struct {
 	Expression string
 	Query      *gojq.Query
+	Code       *gojq.Code // Query compiled with filterVariables
 	Enabled    bool
+	failures   *filterErrors // Lines it failed on at runtime
+}
+
+// filterVariables are the variables filters can reference, in the order
+// their values are passed to Code.Run
+var filterVariables = []string{
+	"$source", // File the line came from in a merged view
+	"$raw",    // The line as it was read, JSON or not
 }
 
 // LogLine represents a single line from the log file
 type LogLine struct {
 	LineNumber int
 	RawLine    string
-	JSONData   map[string]interface{}
+	JSONData   interface{} // Decoded value, nil when evicted to save memory or the line isn't JSON
 	IsValid    bool
+	Source     string // Source file label when several files are merged
+	Truncated  bool   // Whether the line was longer than maxLineSize and cut short
+	Marker     bool   // Whether the line was inserted by sift, e.g. after log rotation
+	Context    bool   // Whether the line is only shown because it's near a filter match
+	Separator  bool   // Whether the line separates groups of context lines, rather than being one
+	Offset     int64  // Where the line starts in the file, or in the decompressed content
 }
 
 // Model represents the state of our TUI application
@@ -96,18 +130,26 @@ type Model struct {
 	width               int
 	showPretty          bool
 	selectedLine        *LogLine
-	prettyViewport      int    // Scroll position in pretty print view
-	fileSize            int64  // Track file size for change detection
-	lastLineNum         int    // Track the last line number for new lines
-	filterMode          bool   // Whether we're in filter input mode
-	filterInput         string // Current filter input
-	filterCursorPos     int    // Cursor position within filter input
-	filterManageMode    bool   // Whether we're in filter management mode
-	filterCursor        int    // Cursor position in filter management
-	filterEditMode      bool   // Whether we're in filter editing mode
-	filterEditInput     string // Current filter edit input
-	filterEditCursorPos int    // Cursor position within filter edit input
-	lineScrollOffset    int    // Horizontal scroll offset for the highlighted line
+	prettyViewport      int             // Scroll position in pretty print view
+	prettyTree          *prettyTree     // The pretty-printed line as a tree, with what's collapsed
+	follow              followState     // Track file size and identity for change detection
+	lastLineNum         int             // Track the last line number for new lines
+	filterMode          bool            // Whether we're in filter input mode
+	filterInput         string          // Current filter input
+	filterCursorPos     int             // Cursor position within filter input
+	filterManageMode    bool            // Whether we're in filter management mode
+	filterCursor        int             // Cursor position in filter management
+	filterEditMode      bool            // Whether we're in filter editing mode
+	filterEditInput     string          // Current filter edit input
+	filterEditCursorPos int             // Cursor position within filter edit input
+	filterJob           *filterJob      // Filtering running in the background, nil when done
+	filtering           backgroundJob   // Runs filterJob
+	invalidLines        invalidLineMode // How filters treat lines that aren't JSON
+	inputErr            error           // Why the expression in the open input failed, nil if it didn't
+	preview             filterPreview   // Feedback on the filter being typed
+	lineScrollOffset    int             // Horizontal scroll offset for the highlighted line
+	contextLines        int             // Lines shown before and after each filter match
+	contextCache        *contextCache   // Lines shown with context, worked out again when they change
 
 	// View transformation fields
 	viewMode       bool        // Whether we're in view transform input mode
@@ -116,12 +158,67 @@ type Model struct {
 	viewFilter     *gojq.Query // Active view transformation filter
 	viewExpression string      // View transformation expression
 
+	// Table of fields shown instead of the lines
+	columns            []column
+	columnOffset       int      // First column shown, when scrolled sideways
+	columnManageMode   bool     // Whether the column picker is open
+	columnCursor       int      // Cursor position in the column picker
+	columnPickerFields []string // Fields of the line under the cursor that aren't columns
+
+	// Field browser fields
+	fieldBrowserMode bool          // Whether the field browser is open
+	fieldCursor      int           // Field selected in the field browser
+	fieldTopValues   bool          // Whether the selected field's top values are shown
+	schema           *schema       // Fields of the lines last scanned, nil before the first scan
+	schemaJob        *schemaJob    // Scan running in the background, nil when done
+	scanning         backgroundJob // Runs schemaJob
+
+	// Stats panel fields
+	showStats bool       // Whether the stats panel is shown next to the lines
+	stats     statsPanel // Field in the stats panel and its figures
+
 	// Lazy loading fields
-	file                *os.File // File handle for lazy loading
-	filePos             int64    // Current file position (bytes read so far)
-	isFileFullyLoaded   bool     // Whether we've read the entire file
-	loadingMoreLines    bool     // Whether we're currently loading more lines
-	estimatedTotalLines int      // Estimated total lines based on file size and average line length
+	file                *logFile        // File handle for lazy loading
+	filePos             int64           // Current file position (bytes read so far)
+	isFileFullyLoaded   bool            // Whether we've read the entire file
+	loadingMoreLines    bool            // Whether we're currently loading more lines
+	estimatedTotalLines int             // Estimated total lines based on file size and average line length
+	compression         compressionType // Compression of the file, which can't be tailed when compressed
+	loadingPrevLines    bool            // Whether we're currently loading the lines above the first one in memory
+	index               *lineIndex      // Offsets of every indexInterval-th line, nil until built
+	indexing            bool            // Whether the index is being built in the background
+	indexCache          bool            // Whether the index is kept in a sidecar file
+	pendingGoto         int             // Line to go to once it can be reached, 0 if none
+	approxLineNumbers   bool            // Whether line numbers are estimated, after reading the end backwards
+	fileReplaced        bool            // Whether the file was rotated or truncated, so offsets of earlier lines are stale
+
+	// Streaming fields (stdin and named pipes)
+	stream      *lineStream // Background reader for non-seekable sources, nil for regular files
+	streamEnded bool        // Whether the stream has reached EOF
+	streamErr   error       // Error that ended the stream, if any
+
+	// Go to line fields
+	gotoMode  bool   // Whether we're in go to line input mode
+	gotoInput string // Line number typed so far
+
+	// Search fields
+	searchMode     bool          // Whether we're in search input mode
+	searchInput    string        // Search typed so far
+	searchRegex    bool          // Whether the search being typed is a regular expression
+	searchBackward bool          // Whether the search being typed was started with ?
+	activeSearch   *search       // Last search, highlighted and repeated with n and N
+	searching      backgroundJob // Searches lines not in memory
+
+	// Tailing fields
+	watcher fileWatcher // Signals changes to the followed files, nil when nothing is tailed
+
+	// Memory fields
+	budget        memoryBudget // Limits on what's kept in memory, unlimited when zero
+	residentBytes int64        // Estimated memory held by the lines, when there's a budget
+
+	// Merged view fields (multiple files)
+	sources   []logSource // Files interleaved into one view, nil for a single file
+	timeQuery *gojq.Code  // Extracts the timestamp used to order merged lines
 
 	// Spinner fields
 	showSpinner  bool // Whether to show the spinner
@@ -138,13 +235,37 @@ type Model struct {
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
-		tickCmd(), // Start the file watching timer
-	)
+	var cmds []tea.Cmd
+	if m.filterJob != nil {
+		cmds = append(cmds, m.filtering.wait()) // Filters given on the command line
+	}
+
+	if m.stream != nil {
+		// Streams deliver their own lines, there is no file to watch
+		return tea.Batch(append(cmds, waitForStreamLines(m.stream))...)
+	}
+
+	cmds = append(cmds, waitForFileEvent(m.watcher, 0)) // Start watching for new lines
+	if m.indexing {
+		cmds = append(cmds, loadLineIndexCmd(m.filename, m.indexCache))
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update handles messages and updates the model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	m = updated.(Model)
+
+	// Any message can change the lines shown, which the stats follow
+	if m.showStats {
+		cmd = tea.Batch(cmd, m.refreshStats())
+	}
+	return m, cmd
+}
+
+// update handles a message
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.height = msg.Height
@@ -170,37 +291,39 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		if m.filterEditMode {
-			// Handle filter edit mode
+			// Handle filter edit mode, any key clears the last error
+			m.inputErr = nil
+			before := m.filterEditInput
 			switch msg.String() {
 			case "esc":
+				m.clearFilterPreview()
 				m.filterEditMode = false
 				m.filterEditInput = ""
 				m.filterEditCursorPos = 0
 			case "enter":
+				var cmd tea.Cmd
 				if m.filterEditInput != "" {
-					// Remember the current line number we're viewing
-					var currentLineNumber int
-					visibleLines := m.getVisibleLines()
-					if m.cursor < len(visibleLines) {
-						currentLineNumber = visibleLines[m.cursor].LineNumber
-					}
-
 					// Try to parse the new filter expression
-					query, err := gojq.Parse(m.filterEditInput)
-					if err == nil {
-						// Update the filter
-						m.filters[m.filterCursor].Expression = m.filterEditInput
-						m.filters[m.filterCursor].Query = query
-						m.applyFilters()
-
-						// Restore position based on line number
-						m.restorePositionAfterFilter(currentLineNumber)
+					filter, err := newFilter(m.filterEditInput)
+					if err != nil {
+						// Keep editing so the expression can be fixed
+						m.showInputError(err, m.filterEditInput, &m.filterEditCursorPos)
+						return m, nil
 					}
-					// If parsing fails, we ignore the edit (could show error in future)
+
+					// Update the filter, keeping its enabled state
+					m.clearFilterPreview()
+					previous := append([]Filter(nil), m.filters...)
+					filter.Enabled = m.filters[m.filterCursor].Enabled
+					m.filters[m.filterCursor] = filter
+
+					// Filter in the background, keeping the cursor on the same line
+					cmd = m.refilter(previous)
 				}
 				m.filterEditMode = false
 				m.filterEditInput = ""
 				m.filterEditCursorPos = 0
+				return m, cmd
 			case "left":
 				if m.filterEditCursorPos > 0 {
 					m.filterEditCursorPos--
@@ -256,34 +379,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.filterEditCursorPos++
 				}
 			}
+
+			// Check the expression again whenever it changes
+			if m.filterEditMode && m.filterEditInput != before {
+				return m, m.updateFilterPreview(m.filterEditInput)
+			}
 			return m, nil
 		}
 
 		if m.filterMode {
-			// Handle filter input mode
+			// Handle filter input mode, any key clears the last error
+			m.inputErr = nil
+			before := m.filterInput
 			switch msg.String() {
 			case "esc":
+				m.clearFilterPreview()
 				m.filterMode = false
 				m.filterInput = ""
 				m.filterCursorPos = 0
 			case "enter":
+				var cmd tea.Cmd
 				if m.filterInput != "" {
-					// Remember the current line number we're viewing
-					var currentLineNumber int
-					visibleLines := m.getVisibleLines()
-					if m.cursor < len(visibleLines) {
-						currentLineNumber = visibleLines[m.cursor].LineNumber
+					previous := append([]Filter(nil), m.filters...)
+					if err := m.addFilter(m.filterInput); err != nil {
+						// Keep the input open so the expression can be fixed
+						m.showInputError(err, m.filterInput, &m.filterCursorPos)
+						return m, nil
 					}
 
-					if err := m.addFilter(m.filterInput); err == nil {
-						m.applyFilters()
-						// Restore position based on line number
-						m.restorePositionAfterFilter(currentLineNumber)
-					}
+					// Filter in the background, keeping the cursor on the same line
+					m.clearFilterPreview()
+					cmd = m.refilter(previous)
 				}
 				m.filterMode = false
 				m.filterInput = ""
 				m.filterCursorPos = 0
+				return m, cmd
 			case "left":
 				if m.filterCursorPos > 0 {
 					m.filterCursorPos--
@@ -339,6 +470,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.filterCursorPos++
 				}
 			}
+
+			// Check the expression again whenever it changes
+			if m.filterMode && m.filterInput != before {
+				return m, m.updateFilterPreview(m.filterInput)
+			}
 			return m, nil
 		}
 
@@ -359,37 +495,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "enter", " ":
 				// Toggle enabled/disabled
 				if m.filterCursor < len(m.filters) {
-					// Remember the current line number we're viewing
-					var currentLineNumber int
-					visibleLines := m.getVisibleLines()
-					if m.cursor < len(visibleLines) {
-						currentLineNumber = visibleLines[m.cursor].LineNumber
-					}
-
+					previous := append([]Filter(nil), m.filters...)
 					m.filters[m.filterCursor].Enabled = !m.filters[m.filterCursor].Enabled
-					m.applyFilters()
 
-					// Restore position based on line number
-					m.restorePositionAfterFilter(currentLineNumber)
+					// Filter in the background, keeping the cursor on the same line
+					return m, m.refilter(previous)
 				}
 			case "d", "x":
 				// Delete filter
 				if m.filterCursor < len(m.filters) {
-					// Remember the current line number we're viewing
-					var currentLineNumber int
-					visibleLines := m.getVisibleLines()
-					if m.cursor < len(visibleLines) {
-						currentLineNumber = visibleLines[m.cursor].LineNumber
-					}
-
+					previous := append([]Filter(nil), m.filters...)
 					m.filters = append(m.filters[:m.filterCursor], m.filters[m.filterCursor+1:]...)
 					if m.filterCursor >= len(m.filters) && len(m.filters) > 0 {
 						m.filterCursor = len(m.filters) - 1
 					}
-					m.applyFilters()
 
-					// Restore position based on line number
-					m.restorePositionAfterFilter(currentLineNumber)
+					// Filter in the background, keeping the cursor on the same line
+					return m, m.refilter(previous)
 				}
 			case "e":
 				// Edit filter
@@ -397,13 +519,79 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.filterEditMode = true
 					m.filterEditInput = m.filters[m.filterCursor].Expression
 					m.filterEditCursorPos = len(m.filterEditInput)
+					return m, m.updateFilterPreview(m.filterEditInput)
+				}
+			}
+			return m, nil
+		}
+
+		if m.columnManageMode {
+			// Handle the column picker
+			switch msg.String() {
+			case "esc", "c":
+				m.columnManageMode = false
+				m.columnCursor = 0
+			case "up", "k":
+				if m.columnCursor > 0 {
+					m.columnCursor--
+				}
+			case "down", "j":
+				if m.columnCursor < len(m.columns)+len(m.columnPickerFields)-1 {
+					m.columnCursor++
+				}
+			case "enter", " ":
+				m.toggleColumn()
+			case "K", "shift+up":
+				m.moveColumn(-1)
+			case "J", "shift+down":
+				m.moveColumn(1)
+			case "<":
+				m.resizeColumn(-1)
+			case ">":
+				m.resizeColumn(1)
+			}
+			return m, nil
+		}
+
+		if m.fieldBrowserMode {
+			// Handle the field browser
+			fields := 0
+			if m.schema != nil {
+				fields = len(m.schema.sorted)
+			}
+			switch msg.String() {
+			case "esc", "b":
+				m.fieldBrowserMode = false
+			case "up", "k":
+				if m.fieldCursor > 0 {
+					m.fieldCursor--
 				}
+			case "down", "j":
+				if m.fieldCursor < fields-1 {
+					m.fieldCursor++
+				}
+			case "pgup", "page_up":
+				m.fieldCursor = max(m.fieldCursor-(m.height-1), 0)
+			case "pgdn", "page_down", "pgdown":
+				m.fieldCursor = max(min(m.fieldCursor+(m.height-1), fields-1), 0)
+			case "c":
+				m.addFieldColumn()
+			case "f":
+				return m, m.filterOnSelectedField()
+			case "s":
+				if field := m.selectedField(); field != nil {
+					m.fieldBrowserMode = false
+					return m, m.openStats(field.path)
+				}
+			case "t", "enter":
+				m.fieldTopValues = !m.fieldTopValues
 			}
 			return m, nil
 		}
 
 		if m.viewMode {
-			// Handle view transform input mode
+			// Handle view transform input mode, any key clears the last error
+			m.inputErr = nil
 			switch msg.String() {
 			case "esc":
 				m.viewMode = false
@@ -412,12 +600,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "enter":
 				if m.viewInput != "" {
 					// Try to compile the view filter
-					query, err := gojq.Parse(m.viewInput)
-					if err == nil {
-						m.viewFilter = query
-						m.viewExpression = m.viewInput
+					query, err := parseViewExpression(m.viewInput)
+					if err != nil {
+						// Keep the input open so the expression can be fixed
+						m.showInputError(err, m.viewInput, &m.viewCursorPos)
+						return m, nil
 					}
-					// If compilation fails, we just ignore the filter (could show error in future)
+					m.viewFilter = query
+					m.viewExpression = m.viewInput
 				} else {
 					// Empty input clears the view filter
 					m.viewFilter = nil
@@ -484,12 +674,94 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.gotoMode {
+			// Handle go to line input mode
+			switch key := msg.String(); key {
+			case "esc":
+				m.gotoMode = false
+				m.gotoInput = ""
+			case "enter":
+				lineNumber, err := strconv.Atoi(m.gotoInput)
+				m.gotoMode = false
+				m.gotoInput = ""
+				if err == nil {
+					return m, m.goToLine(lineNumber)
+				}
+			case "backspace":
+				if len(m.gotoInput) > 0 {
+					m.gotoInput = m.gotoInput[:len(m.gotoInput)-1]
+				}
+			default:
+				if len(key) == 1 && key >= "0" && key <= "9" {
+					m.gotoInput += key
+				}
+			}
+			return m, nil
+		}
+
+		if m.searchMode {
+			// Handle search input mode, any key clears the last error
+			m.inputErr = nil
+			switch key := msg.String(); key {
+			case "esc":
+				m.searchMode = false
+				m.searchInput = ""
+			case "enter":
+				return m, m.submitSearch()
+			case "ctrl+r":
+				m.searchRegex = !m.searchRegex
+			case "backspace":
+				if len(m.searchInput) > 0 {
+					m.searchInput = m.searchInput[:len(m.searchInput)-1]
+				}
+			case "ctrl+v":
+				m.searchInput += getClipboardText()
+			default:
+				if len(key) == 1 && key >= " " && key <= "~" {
+					m.searchInput += key
+				}
+			}
+			return m, nil
+		}
+
+		// Keys in the pretty view that filter on the value under the cursor
+		if kind, ok := fieldFilterKeys[msg.String()]; ok && m.showPretty {
+			return m, m.filterOnField(kind)
+		}
+
 		// Normal mode key handling
 		switch msg.String() {
 		case "ctrl+c", "q":
 			m.cleanup()
 			return m, tea.Quit
 
+		case "/", "?":
+			if !m.showPretty && !m.showHelp {
+				m.openSearch(msg.String() == "?")
+			}
+
+		case "i":
+			if !m.showPretty && !m.showHelp {
+				return m, m.cycleInvalidLines()
+			}
+
+		case "+", "=":
+			if !m.showPretty && !m.showHelp {
+				m.setContextLines(m.contextLines + 1)
+			}
+
+		case "-":
+			if !m.showPretty && !m.showHelp {
+				m.setContextLines(m.contextLines - 1)
+			}
+
+		case "n", "N":
+			if m.showPretty {
+				m.prettySearchNext(msg.String() == "N")
+			} else if !m.showHelp {
+				return m, m.searchNext(msg.String() == "N")
+			}
+
 		case "f":
 			if !m.showPretty && !m.filterManageMode && !m.viewMode {
 				m.filterMode = true
@@ -503,6 +775,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filterCursor = 0
 			}
 
+		case "c":
+			if !m.showPretty && !m.showHelp {
+				m.openColumnPicker()
+			}
+
+		case "b":
+			if !m.showPretty && !m.showHelp {
+				return m, m.openFieldBrowser()
+			}
+
+		case "%":
+			if m.showPretty {
+				// Stats of the value under the cursor, unless it's the whole line
+				if tree := m.tree(); tree != nil && tree.cursor != tree.root {
+					path := tree.cursor.path()
+					m.closePretty()
+					return m, m.openStats(path)
+				}
+			} else if !m.showHelp {
+				return m, m.toggleStats()
+			}
+
 		case "v", "V":
 			if !m.showPretty && !m.filterMode && !m.filterManageMode {
 				m.viewMode = true
@@ -514,33 +808,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !m.showPretty && !m.filterMode && !m.filterManageMode && !m.viewMode {
 				m.tailMode = !m.tailMode
 
-				// If tail mode is now enabled, load the entire file and jump to the end
+				// If tail mode is now enabled, read to the end of the file and jump there
 				if m.tailMode {
-					if !m.isFileFullyLoaded {
-						// Start spinner and trigger loading to end
-						m.showSpinner = true
-						m.spinnerFrame = 0
-						return m, tea.Batch(
-							spinnerTickCmd(),
-							loadToEndCmd(m.filename, m.file, len(m.lines)),
-						)
-					} else {
-						// File already fully loaded, jump immediately
-						visibleLines := m.getVisibleLines()
-						if len(visibleLines) > 0 {
-							m.cursor = len(visibleLines) - 1
-							// Adjust viewport to show the last line at the bottom
-							if m.cursor >= m.height-1 { // Account for status bar only
-								m.viewport = m.cursor - m.height + 2
-								if m.viewport < 0 {
-									m.viewport = 0
-								}
-							} else {
-								m.viewport = 0
-							}
-							m.lineScrollOffset = 0
-						}
-					}
+					return m, m.goToLine(lastLine)
 				}
 			}
 
@@ -549,6 +819,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.showHelp = !m.showHelp
 			}
 
+		case ":":
+			if !m.showPretty && !m.showHelp && !m.filterMode && !m.filterManageMode && !m.viewMode {
+				m.gotoMode = true
+				m.gotoInput = ""
+			}
+
 		case "up", "k":
 			if m.showHelp {
 				// Scroll up in help view
@@ -556,8 +832,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.helpViewport--
 				}
 			} else if m.showPretty {
-				// Scroll up in pretty print view
-				if m.prettyViewport > 0 {
+				// Move up the tree, or scroll up lines that aren't JSON
+				if tree := m.tree(); tree != nil {
+					tree.move(-1)
+					m.scrollToPrettyCursor()
+				} else if m.prettyViewport > 0 {
 					m.prettyViewport--
 				}
 			} else {
@@ -570,6 +849,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				// Reset horizontal scroll when moving vertically
 				m.lineScrollOffset = 0
+
+				// Load the lines above a window reached through the index
+				if cmd := m.loadPrevLinesIfNeeded(); cmd != nil {
+					return m, cmd
+				}
 			}
 
 		case "down", "j":
@@ -580,9 +864,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.helpViewport++
 				}
 			} else if m.showPretty {
-				// Scroll down in pretty print view with bounds checking
-				maxScroll := m.calculatePrettyMaxScroll()
-				if m.prettyViewport < maxScroll {
+				// Move down the tree, or scroll down lines that aren't JSON
+				if tree := m.tree(); tree != nil {
+					tree.move(1)
+					m.scrollToPrettyCursor()
+				} else if maxScroll := m.calculatePrettyMaxScroll(); m.prettyViewport < maxScroll {
 					m.prettyViewport++
 				}
 			} else {
@@ -601,11 +887,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if !m.isFileFullyLoaded && !m.loadingMoreLines &&
 						len(m.lines)-m.cursor <= loadTriggerThreshold {
 						m.loadingMoreLines = true
-						return m, tea.Cmd(func() tea.Msg {
-							const chunkSize = 500 // Load 500 lines at a time
-							err := m.loadMoreLines(chunkSize)
-							return loadMoreLinesMsg{err: err}
-						})
+						return m, m.nextChunkCmd()
 					}
 				}
 				// Reset horizontal scroll when moving vertically
@@ -613,20 +895,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "left":
-			if !m.showPretty {
+			if len(m.columns) > 0 && !m.showPretty {
+				// Scroll the table a column to the left
+				m.scrollColumns(-1)
+			} else if !m.showPretty {
 				// Scroll highlighted line to the left
 				if m.lineScrollOffset > 0 {
 					m.lineScrollOffset--
 				}
+			} else if tree := m.tree(); tree != nil {
+				// Collapse the value under the cursor, or go to its parent
+				tree.collapse()
+				m.scrollToPrettyCursor()
 			}
 
 		case "right":
-			if !m.showPretty {
+			if len(m.columns) > 0 && !m.showPretty {
+				// Scroll the table a column to the right
+				m.scrollColumns(1)
+			} else if !m.showPretty {
 				// Scroll highlighted line to the right
 				visibleLines := m.getVisibleLines()
 				if m.cursor < len(visibleLines) {
 					line := visibleLines[m.cursor]
-					maxWidth := m.width - 3 // Account for cursor + reserved rightmost column
+					maxWidth := m.width - 3 - m.sourceColumnWidth() // Account for cursor + source column + reserved rightmost column
 					if len(line.RawLine) > maxWidth {
 						maxScroll := len(line.RawLine) - maxWidth
 						if m.lineScrollOffset < maxScroll {
@@ -634,10 +926,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 				}
+			} else if tree := m.tree(); tree != nil {
+				// Expand the value under the cursor, or go to its first child
+				tree.expand()
+				m.scrollToPrettyCursor()
+			}
+
+		case "tab", "shift+tab":
+			if tree := m.tree(); m.showPretty && tree != nil {
+				if msg.String() == "tab" {
+					tree.sibling(1)
+				} else {
+					tree.sibling(-1)
+				}
+				m.scrollToPrettyCursor()
+			}
+
+		case "E", "C":
+			if tree := m.tree(); m.showPretty && tree != nil {
+				// Expand or collapse everything at once
+				tree.setCollapsed(msg.String() == "C")
+				m.scrollToPrettyCursor()
 			}
 
 		case "ctrl+left":
-			if !m.showPretty {
+			if len(m.columns) > 0 && !m.showPretty {
+				m.scrollColumns(-1)
+			} else if !m.showPretty {
 				// Fast scroll highlighted line to the left
 				if m.lineScrollOffset > 0 {
 					m.lineScrollOffset -= 5
@@ -648,12 +963,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "ctrl+right":
-			if !m.showPretty {
+			if len(m.columns) > 0 && !m.showPretty {
+				m.scrollColumns(1)
+			} else if !m.showPretty {
 				// Fast scroll highlighted line to the right
 				visibleLines := m.getVisibleLines()
 				if m.cursor < len(visibleLines) {
 					line := visibleLines[m.cursor]
-					maxWidth := m.width - 3 // Account for cursor + reserved rightmost column
+					maxWidth := m.width - 3 - m.sourceColumnWidth() // Account for cursor + source column + reserved rightmost column
 					if len(line.RawLine) > maxWidth {
 						maxScroll := len(line.RawLine) - maxWidth
 						m.lineScrollOffset += 5
@@ -685,6 +1002,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.prettyViewport < 0 {
 					m.prettyViewport = 0
 				}
+				m.keepPrettyCursorOnScreen()
 			} else {
 				// Page up in main log view
 				visibleLines := m.getVisibleLines()
@@ -706,6 +1024,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				// Reset horizontal scroll when moving vertically
 				m.lineScrollOffset = 0
+
+				// Load the lines above a window reached through the index
+				if cmd := m.loadPrevLinesIfNeeded(); cmd != nil {
+					return m, cmd
+				}
 			}
 
 		case "pgdn", "page_down", "pgdown":
@@ -731,6 +1054,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.prettyViewport > maxScroll {
 					m.prettyViewport = maxScroll
 				}
+				m.keepPrettyCursorOnScreen()
 			} else {
 				// Page down in main log view
 				visibleLines := m.getVisibleLines()
@@ -756,11 +1080,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if !m.isFileFullyLoaded && !m.loadingMoreLines &&
 						len(m.lines)-m.cursor <= loadTriggerThreshold {
 						m.loadingMoreLines = true
-						return m, tea.Cmd(func() tea.Msg {
-							const chunkSize = 500 // Load 500 lines at a time
-							err := m.loadMoreLines(chunkSize)
-							return loadMoreLinesMsg{err: err}
-						})
+						return m, m.nextChunkCmd()
 					}
 				}
 				// Reset horizontal scroll when moving vertically
@@ -772,14 +1092,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Do nothing when help screen is open
 			} else if m.showPretty {
 				// Close pretty print view
-				m.showPretty = false
-				m.selectedLine = nil
-				m.prettyViewport = 0
+				m.closePretty()
 			} else {
 				visibleLines := m.getVisibleLines()
-				if m.cursor < len(visibleLines) {
+				if m.cursor < len(visibleLines) && !visibleLines[m.cursor].Separator {
 					// Open pretty print view
 					m.selectedLine = &visibleLines[m.cursor]
+					m.prettyTree = newPrettyTree(m.selectedLine)
 					m.showPretty = true
 					m.prettyViewport = 0 // Reset scroll position
 				}
@@ -791,9 +1110,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.showHelp = false
 			} else if m.showPretty {
 				// Close pretty print view
-				m.showPretty = false
-				m.selectedLine = nil
-				m.prettyViewport = 0
+				m.closePretty()
+			} else if m.showStats {
+				m.closeStats()
+			} else if m.filterJob != nil {
+				// Stop filtering and go back to the filters from before
+				return m, m.cancelFiltering()
+			} else if m.searching.running() {
+				// Stop searching the lines not in memory
+				m.cancelSearch()
 			} else {
 				// Quit the application
 				m.cleanup()
@@ -802,6 +1127,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "home":
 			if !m.showPretty {
+				// The first line isn't in memory after jumping through the index
+				if m.hasLinesAbove() {
+					return m, m.goToLine(1)
+				}
+
 				// Jump to first line
 				m.cursor = 0
 				m.viewport = 0
@@ -811,92 +1141,127 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "end":
 			if !m.showPretty {
-				// Check if we need to load more data
-				if !m.isFileFullyLoaded {
-					// Start spinner and trigger loading
-					m.showSpinner = true
-					m.spinnerFrame = 0
-					return m, tea.Batch(
-						spinnerTickCmd(),
-						loadToEndCmd(m.filename, m.file, len(m.lines)),
-					)
-				} else {
-					// File already fully loaded, jump immediately
-					visibleLines := m.getVisibleLines()
-					if len(visibleLines) > 0 {
-						m.cursor = len(visibleLines) - 1
-						// Adjust viewport to show the last line at the bottom
-						if m.cursor >= m.height-1 { // Account for status bar only
-							m.viewport = m.cursor - m.height + 2
-						} else {
-							m.viewport = 0
-						}
-						m.lineScrollOffset = 0
-					}
-				}
+				// Jumps immediately when the end is in memory, and loads it
+				// (through the index when there is one) otherwise
+				return m, m.goToLine(lastLine)
 			}
 		}
 
-	case tickMsg:
+	case fileEventMsg:
+		// Compressed files are archives that aren't appended to, stop watching
+		if m.compression != compressionNone {
+			return m, nil
+		}
+
+		// Lines appended to a file that isn't loaded to the end yet are
+		// picked up by lazy loading, tailing resumes once it reaches the end
+		if !m.isFileFullyLoaded {
+			return m, waitForFileEvent(m.watcher, 0)
+		}
+
+		// Check for new lines in every merged file
+		if len(m.sources) > 0 {
+			return m, checkSourcesForNewLines(m.sources, m.timeQuery, m.lastLineNum)
+		}
+
 		// Check for new lines in the file
-		return m, tea.Batch(
-			checkForNewLines(m.filename, m.fileSize, m.lastLineNum),
-			tickCmd(), // Always schedule the next tick
-		)
+		return m, checkForNewLines(m.filename, m.follow, m.lastLineNum)
 
 	case newLinesMsg:
-		// Add new lines to the model
-		newLines := []LogLine(msg)
-		if len(newLines) > 0 {
-			// Update state
-			m.lines = append(m.lines, newLines...)
-			m.lastLineNum = newLines[len(newLines)-1].LineNumber
+		m.appendNewLines(msg)
 
-			// Apply filters to new lines if filters exist
-			if len(m.filters) > 0 {
-				m.applyFilters()
-			}
+		// Keep reading from the stream until it ends
+		if m.stream != nil {
+			return m, waitForStreamLines(m.stream)
+		}
 
-			// If tail mode is enabled, jump to the bottom automatically
-			// This must happen AFTER filters are applied
-			if m.tailMode {
-				visibleLines := m.getVisibleLines()
-				if len(visibleLines) > 0 {
-					m.cursor = len(visibleLines) - 1
-					// Adjust viewport to show the last line at the bottom
-					if m.cursor >= m.height-1 { // Account for status bar only
-						m.viewport = m.cursor - m.height + 2
-						if m.viewport < 0 {
-							m.viewport = 0
-						}
-					} else {
-						m.viewport = 0
-					}
-					m.lineScrollOffset = 0
-				}
+		return m, nil
+
+	case followMsg:
+		// Lines in memory were replaced by a jump while checking, the check
+		// read from where the old window ended
+		if msg.firstLineNumber != m.lastLineNum+1 {
+			return m, waitForFileEvent(m.watcher, m.partialLineWait())
+		}
+
+		// Continue from where this check stopped, which is the start of the
+		// new file after a rotation
+		if len(m.sources) > 0 {
+			for i := range m.sources {
+				m.sources[i].follow = msg.states[i]
 			}
+		} else if len(msg.states) > 0 {
+			m.follow = msg.states[0]
+		}
+		m.finishLines(msg.finished)
+		m.appendNewLines(msg.newLines)
 
-			// Update file size (we need to get current file size)
-			if stat, err := os.Stat(m.filename); err == nil {
-				m.fileSize = stat.Size()
+		// The index and offsets describe the file before it was rotated or truncated
+		for _, line := range msg.newLines {
+			if line.Marker {
+				m.index = nil
+				m.fileReplaced = true
+				break
 			}
 		}
-		// Don't schedule another tick here - tickMsg handler does it
+
+		// Only wait for the next change once this check is done, so checks
+		// never overlap and read the same lines twice
+		return m, waitForFileEvent(m.watcher, m.partialLineWait())
+
+	case searchResultMsg:
+		return m, m.handleSearchResult(msg)
+
+	case filterPreviewTickMsg:
+		return m, m.handleFilterPreviewTick(msg)
+
+	case filterPreviewMsg:
+		m.handleFilterPreview(msg)
+		return m, nil
+
+	case filterProgressMsg:
+		// Ignore chunks from a job that was cancelled or replaced
+		if m.filtering.isStale(msg.seq) {
+			return m, nil
+		}
+		return m, m.handleFilterProgress(msg)
+
+	case schemaProgressMsg:
+		return m, m.handleSchemaProgress(msg)
+
+	case statsMsg:
+		m.handleStats(msg)
+		return m, nil
+
+	case streamEndedMsg:
+		m.streamEnded = true
+		m.streamErr = msg.err
 		return m, nil
 
 	case loadMoreLinesMsg:
 		m.loadingMoreLines = false
-		if msg.err != nil {
+		if msg.file != nil {
+			m.file = msg.file
+		}
+		if len(msg.newLines) > 0 {
+			m.lines = append(m.lines, msg.newLines...)
+			m.lastLineNum = msg.newLines[len(msg.newLines)-1].LineNumber
+		}
+		if msg.err != nil || msg.isComplete {
 			// Could show error to user if needed
 			// For now, silently fail and stop trying to load more
 			m.isFileFullyLoaded = true
-		} else {
-			// Apply filters to the new lines
-			if len(m.filters) > 0 {
-				m.applyFilters()
+			if msg.err == nil {
+				m.closeLoadedFile()
+			} else if m.file != nil {
+				m.file.Close()
+				m.file = nil
 			}
 		}
-		return m, nil
+		// Apply filters to the new lines
+		m.filterAppendedLines(msg.newLines)
+		m.enforceMemoryBudget()
+		return m, m.resumePendingGoto()
 
 	case spinnerTickMsg:
 		if m.showSpinner {
@@ -930,6 +1295,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if len(msg.newLines) > 0 {
 			m.lines = append(m.lines, msg.newLines...)
 			m.lastLineNum = msg.newLines[len(msg.newLines)-1].LineNumber
+			m.filterAppendedLines(msg.newLines)
+		}
+
+		// Keep the handle so the next chunk continues where this one stopped
+		if msg.file != nil {
+			m.file = msg.file
 		}
 
 		if msg.err != nil || msg.isComplete {
@@ -939,16 +1310,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.spinnerFrame = 0
 
 			// Close file handle if we're done
-			if m.file != nil {
+			if msg.err == nil {
+				m.closeLoadedFile()
+			} else if m.file != nil {
 				m.file.Close()
 				m.file = nil
 			}
 
-			// Apply filters to newly loaded lines
-			if len(m.filters) > 0 {
-				m.applyFilters()
-			}
-
 			// Jump to last line
 			visibleLines := m.getVisibleLines()
 			if len(visibleLines) > 0 {
@@ -961,12 +1329,110 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.lineScrollOffset = 0
 			}
+			m.enforceMemoryBudget()
 
-			return m, nil
+			// Unless a specific line was asked for
+			return m, m.resumePendingGoto()
 		} else {
 			// Continue loading more chunks
-			return m, loadToEndCmd(m.filename, m.file, len(m.lines))
+			m.enforceMemoryBudget()
+			return m, loadToEndCmd(m.filename, m.file, m.index, m.lastLineNum)
+		}
+
+	case jumpMsg:
+		m.showSpinner = false
+		m.spinnerFrame = 0
+
+		if msg.err != nil || len(msg.newLines) == 0 {
+			if msg.file != nil {
+				msg.file.Close()
+			}
+			return m, m.resumePendingGoto()
+		}
+
+		// Replace the lines in memory with the window around the target
+		if m.file != nil {
+			m.file.Close()
+		}
+		m.lines = msg.newLines
+		m.lastLineNum = msg.newLines[len(msg.newLines)-1].LineNumber
+		m.approxLineNumbers = msg.approx
+		m.file = msg.file
+		m.isFileFullyLoaded = msg.atEOF
+		if msg.atEOF {
+			m.closeLoadedFile()
+		}
+		m.applyFilters()
+
+		if msg.target == lastLine {
+			m.moveCursorToEnd()
+		} else {
+			m.restorePositionAfterFilter(msg.target)
+			m.viewport = m.cursor // Show the target at the top, like less
+		}
+		m.enforceMemoryBudget()
+
+		// Number the window exactly as soon as the index allows
+		var numberCmd tea.Cmd
+		if m.approxLineNumbers && m.index != nil {
+			numberCmd = lineNumberAtCmd(m.filename, m.index, m.lines[0].Offset)
+		}
+		return m, tea.Batch(numberCmd, m.loadPrevLinesIfNeeded(), m.resumePendingGoto())
+
+	case prevLinesMsg:
+		m.loadingPrevLines = false
+
+		// Lines loaded for a window that has since been replaced or
+		// renumbered don't fit
+		if msg.err == nil && len(msg.newLines) > 0 && m.hasLinesAbove() && msg.before == m.lines[0].Offset &&
+			msg.newLines[len(msg.newLines)-1].LineNumber == m.lines[0].LineNumber-1 {
+			// Prepend the lines, keeping the cursor on the same line
+			before := len(m.getVisibleLines())
+			m.lines = append(msg.newLines, m.lines...)
+			m.filterPrependedLines(msg.newLines)
+			if m.approxLineNumbers {
+				// Reaching the start of the file makes the numbers exact,
+				// otherwise keep estimates from running into it
+				if msg.fromStart {
+					m.renumberLines(1)
+				} else if m.lines[0].LineNumber < 2 {
+					m.renumberLines(2)
+				}
+			}
+			added := len(m.getVisibleLines()) - before
+			m.cursor += added
+			m.viewport += added
+			m.enforceMemoryBudget()
+		}
+		return m, m.resumePendingGoto()
+
+	case lineIndexMsg:
+		m.indexing = false
+		if msg.err == nil && !m.fileReplaced {
+			m.index = msg.index
+			if m.approxLineNumbers && len(m.lines) > 0 {
+				return m, lineNumberAtCmd(m.filename, m.index, m.lines[0].Offset)
+			}
+		}
+		return m, m.resumePendingGoto()
+
+	case lineNumberMsg:
+		if !m.approxLineNumbers || len(m.lines) == 0 {
+			return m, nil
+		}
+		if m.lines[0].Offset != msg.offset {
+			// Lines were loaded or dropped above while counting, count again
+			if m.index != nil {
+				return m, lineNumberAtCmd(m.filename, m.index, m.lines[0].Offset)
+			}
+			return m, nil
+		}
+		if msg.err != nil {
+			m.index = nil // The index no longer matches the file
+		} else {
+			m.renumberLines(msg.lineNumber)
 		}
+		return m, m.resumePendingGoto()
 	}
 
 	return m, nil
@@ -986,6 +1452,26 @@ func (m Model) View() string {
 		return m.renderFilterManageView()
 	}
 
+	if m.columnManageMode {
+		return m.renderColumnManageView()
+	}
+
+	if m.fieldBrowserMode {
+		return m.renderFieldBrowser()
+	}
+
+	if m.showStats {
+		return m.renderStats()
+	}
+
+	displayLines := m.getVisibleLines()
+	return m.renderLogLines(displayLines, m.width) + m.renderStatusBar(displayLines)
+}
+
+// renderLogLines renders the lines to display in the main view fitted to
+// width, a line each down to the status bar
+func (m Model) renderLogLines(displayLines []LogLine, width int) string {
+	m.width = width
 	var s strings.Builder
 
 	// Calculate available space for log lines
@@ -996,8 +1482,16 @@ func (m Model) View() string {
 		visibleLines = 1
 	}
 
-	// Get the lines to display (filtered or all)
-	displayLines := m.getVisibleLines()
+	// The table's header takes a line at the top
+	if len(m.columns) > 0 {
+		header := strings.Repeat(" ", 2+m.sourceColumnWidth()) + tableHeader(m.shownColumns())
+		if m.width > 15 {
+			header = truncateText(header, m.width-1)
+		}
+		s.WriteString(columnHeaderStyle.Render(header))
+		s.WriteString("\n")
+		visibleLines = max(visibleLines-1, 1)
+	}
 
 	// Check if all lines are filtered out
 	if len(displayLines) == 0 && len(m.lines) > 0 {
@@ -1008,6 +1502,10 @@ func (m Model) View() string {
 		}
 	} else {
 		start := m.viewport
+		if m.cursor >= start+visibleLines {
+			// The cursor is kept on screen without the header, which takes its line
+			start = m.cursor - visibleLines + 1
+		}
 		end := start + visibleLines
 		if end > len(displayLines) {
 			end = len(displayLines)
@@ -1024,37 +1522,77 @@ func (m Model) View() string {
 			if i == m.cursor {
 				style = selectedLineStyle
 				cursor = "> "
+			} else if line.Separator {
+				style = separatorLineStyle
+			} else if line.Marker {
+				style = markerLineStyle
+			} else if line.Context {
+				style = contextLineStyle
 			} else if !line.IsValid {
 				style = invalidLineStyle
 			}
 
+			// Separators between groups of context lines are drawn like grep's
+			if line.Separator {
+				s.WriteString(style.Render(cursor + "--"))
+				s.WriteString("\n")
+				linesDisplayed++
+				continue
+			}
+
 			// Truncate line if too long, accounting for horizontal scroll
 			displayLine := line.RawLine
 
-			// Apply view transformation if active
-			if m.viewFilter != nil && line.IsValid {
-				if transformedData := m.applyViewTransform(line.JSONData); transformedData != "" {
+			// Show the line as a row of the table, or apply view
+			// transformation if active
+			var viewErr error
+			if len(m.columns) > 0 && line.IsValid {
+				displayLine = tableRow(m.shownColumns(), line)
+			} else if m.viewFilter != nil && line.IsValid {
+				transformedData, err := m.viewTransform(line.data())
+				if transformedData != "" {
 					displayLine = transformedData
 				}
 				// If transformation fails or returns empty, displayLine remains as line.RawLine
+				viewErr = err
 			}
 
-			maxWidth := m.width - 3 // Account for cursor + reserved rightmost column
+			maxWidth := m.width - 3 - m.sourceColumnWidth() // Account for cursor + source column + reserved rightmost column
 
 			// Apply horizontal scrolling for the selected line
 			if i == m.cursor && m.lineScrollOffset > 0 && len(displayLine) > m.lineScrollOffset {
 				displayLine = displayLine[m.lineScrollOffset:]
 			}
 
-			if m.width > 15 && len(displayLine) > maxWidth && maxWidth > 3 {
-				displayLine = displayLine[:maxWidth-3] + "..."
+			if m.width > 15 && maxWidth > 3 {
+				displayLine = truncateText(displayLine, maxWidth)
 			}
 
-			lineText := fmt.Sprintf("%s%s", cursor, displayLine)
-			if !line.IsValid {
-				lineText += " [INVALID JSON]"
+			// Find search matches in what's shown, before anything is added
+			matches := m.searchMatches(displayLine)
+
+			if line.Truncated {
+				displayLine += " [TRUNCATED]"
+			} else if !line.IsValid && !line.Marker {
+				displayLine += " [INVALID JSON]"
+			} else if viewErr != nil {
+				displayLine += fmt.Sprintf(" [VIEW ERROR: %v]", viewErr)
+			}
+
+			sourceLabel := m.renderSourceLabel(line, i == m.cursor)
+			if len(matches) > 0 {
+				// Every part is styled by itself, as the matches reset the style
+				base := style.UnsetPadding()
+				cursor = base.Render(cursor)
+				displayLine = highlightMatches(displayLine, matches, base)
+			} else if sourceLabel != "" && i != m.cursor && !line.IsValid {
+				// Keep the gray of invalid lines off the colored source column
+				displayLine = style.UnsetPadding().Render(displayLine)
+				style = lineStyle
 			}
 
+			lineText := fmt.Sprintf("%s%s%s", cursor, sourceLabel, displayLine)
+
 			s.WriteString(style.Render(lineText))
 			s.WriteString("\n")
 			linesDisplayed++
@@ -1067,12 +1605,18 @@ func (m Model) View() string {
 		}
 	}
 
-	// Status bar (pinned to bottom)
+	return s.String()
+}
+
+// renderStatusBar renders the status bar pinned to the bottom of the main
+// view, or the input being typed, counting the lines to display
+func (m Model) renderStatusBar(displayLines []LogLine) string {
 	var status string
 	if m.filterMode {
 		// Create the complete filter bar content
 		filterPrefix := "Filter: "
-		completeContent := filterPrefix + m.filterInput
+		completeContent := filterPrefix + m.filterInput + m.filterInputHint()
+		background, foreground := m.filterInputColors("#FFD700", "#000000")
 
 		// Calculate padding needed to fill the entire width (reserve rightmost column)
 		contentLen := len(completeContent)
@@ -1090,11 +1634,11 @@ func (m Model) View() string {
 			afterCursor := completeContent[prefixLen+len(m.filterInput):]
 
 			normalStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FFD700")).
-				Foreground(lipgloss.Color("#000000"))
+				Background(lipgloss.Color(background)).
+				Foreground(lipgloss.Color(foreground))
 			cursorStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#000000")).
-				Foreground(lipgloss.Color("#FFD700"))
+				Background(lipgloss.Color(foreground)).
+				Foreground(lipgloss.Color(background))
 
 			if len(afterCursor) > 0 {
 				styledContent = normalStyle.Render(beforeCursor) + cursorStyle.Render(string(afterCursor[0])) + normalStyle.Render(afterCursor[1:])
@@ -1108,20 +1652,51 @@ func (m Model) View() string {
 			afterCursor := completeContent[prefixLen+m.filterCursorPos+1:]
 
 			normalStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FFD700")).
-				Foreground(lipgloss.Color("#000000"))
+				Background(lipgloss.Color(background)).
+				Foreground(lipgloss.Color(foreground))
 			cursorStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#000000")).
-				Foreground(lipgloss.Color("#FFD700"))
+				Background(lipgloss.Color(foreground)).
+				Foreground(lipgloss.Color(background))
 
 			styledContent = normalStyle.Render(beforeCursor) + cursorStyle.Render(cursorChar) + normalStyle.Render(afterCursor)
 		}
 
 		status = styledContent
+	} else if m.gotoMode {
+		// Same layout as the other prompts, the cursor always sits at the end
+		content := "Go to line: " + m.gotoInput
+		normalStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color("#4A90E2")).
+			Foreground(lipgloss.Color("#FFFFFF"))
+		cursorStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color("#FFFFFF")).
+			Foreground(lipgloss.Color("#4A90E2"))
+
+		padding := ""
+		if len(content) < m.width-2 {
+			padding = strings.Repeat(" ", m.width-2-len(content))
+		}
+		status = normalStyle.Render(content) + cursorStyle.Render(" ") + normalStyle.Render(padding)
+	} else if m.searchMode {
+		// Same layout as go to line, with any error after the cursor
+		content := m.searchPrompt()
+		normalStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color("#2E8B57")).
+			Foreground(lipgloss.Color("#FFFFFF"))
+		cursorStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color("#FFFFFF")).
+			Foreground(lipgloss.Color("#2E8B57"))
+
+		errText := m.inputErrorText()
+		padding := ""
+		if len(content)+len(errText) < m.width-2 {
+			padding = strings.Repeat(" ", m.width-2-len(content)-len(errText))
+		}
+		status = normalStyle.Render(content) + cursorStyle.Render(" ") + normalStyle.Render(errText+padding)
 	} else if m.viewMode {
 		// Create the complete view transform bar content
 		viewPrefix := "View: "
-		completeContent := viewPrefix + m.viewInput
+		completeContent := viewPrefix + m.viewInput + m.inputErrorText()
 
 		// Calculate padding needed to fill the entire width (reserve rightmost column)
 		contentLen := len(completeContent)
@@ -1183,16 +1758,34 @@ func (m Model) View() string {
 		} else {
 			controls += " | T=off"
 		}
+		if m.contextLines > 0 {
+			controls += fmt.Sprintf(" | C=%d", m.contextLines)
+		}
+		if len(m.filters) > 0 {
+			controls += " | I=" + m.invalidLines.String()
+		}
 
-		// Determine total count for status
+		// Determine total count for status, context lines aside
 		totalCount := len(displayLines)
+		if len(m.filters) > 0 {
+			totalCount = len(m.filteredLines)
+		}
 		totalIndicator := ""
 		if !m.isFileFullyLoaded {
-			if m.estimatedTotalLines > len(m.lines) {
+			if m.index != nil && m.index.lines >= m.lastLineNum {
+				// Counted exactly while indexing
+				totalIndicator = humanize.Comma(int64(m.index.lines))
+			} else if m.estimatedTotalLines > m.lastLineNum {
 				totalIndicator = fmt.Sprintf("~%s", humanize.Comma(int64(m.estimatedTotalLines)))
 			} else {
-				totalIndicator = fmt.Sprintf("%s+", humanize.Comma(int64(len(m.lines))))
+				totalIndicator = fmt.Sprintf("%s+", humanize.Comma(int64(m.lastLineNum)))
 			}
+		} else if m.approxLineNumbers {
+			// Read backwards from the end, nothing before the window was counted
+			totalIndicator = "~" + humanize.Comma(int64(m.lastLineNum))
+		} else if m.hasLinesAbove() {
+			// Lines before the window aren't in memory, but the file ends here
+			totalIndicator = humanize.Comma(int64(m.lastLineNum))
 		} else {
 			totalIndicator = humanize.Comma(int64(totalCount))
 		}
@@ -1203,10 +1796,44 @@ func (m Model) View() string {
 			currentLineNumber = displayLines[m.cursor].LineNumber
 		}
 
+		// Show when a stream has finished so it's clear no more lines will arrive
+		sourceName := m.filename
+		if m.compression != compressionNone {
+			sourceName += fmt.Sprintf(" (%s)", m.compression)
+		}
+		if m.streamErr != nil {
+			sourceName += fmt.Sprintf(" (error: %v)", m.streamErr)
+		} else if m.streamEnded {
+			sourceName += " (EOF)"
+		}
+		if m.indexing {
+			sourceName += " (indexing)"
+		}
+
+		// Show what's held in memory when it's limited
+		resident := ""
+		if m.budget.enabled() {
+			resident = fmt.Sprintf(" | Mem %s lines, ~%s", humanize.Comma(int64(len(m.lines))), humanize.IBytes(uint64(m.residentBytes)))
+		}
+
+		// Show how far background filtering has got
+		filtering := ""
+		if m.filterJob != nil {
+			filtering = fmt.Sprintf(" | Filtering %d%% (Esc to cancel)", m.filterJob.percent())
+		}
+		if count := m.filterErrorCount(); count > 0 {
+			filtering += fmt.Sprintf(" | %s filter errors (F)", humanize.Comma(int64(count)))
+		}
+
+		currentIndicator := humanize.Comma(int64(currentLineNumber))
+		if m.approxLineNumbers {
+			currentIndicator = "~" + currentIndicator
+		}
+
 		// Create main status text without spinner
 		statusText := fmt.Sprintf(
-			"%s | Line %s/%s | %s",
-			m.filename, humanize.Comma(int64(currentLineNumber)), totalIndicator, controls,
+			"%s | Line %s/%s%s%s%s | %s",
+			sourceName, currentIndicator, totalIndicator, filtering, m.searchStatus(), resident, controls,
 		)
 
 		// Add spinner to the right edge if active
@@ -1235,9 +1862,7 @@ func (m Model) View() string {
 			status = statusStyle.Width(m.width - 1).Render(statusText)
 		}
 	}
-	s.WriteString(status)
-
-	return s.String()
+	return status
 }
 
 // renderPrettyView renders the pretty-printed JSON view
@@ -1251,38 +1876,7 @@ func (m Model) renderPrettyView() string {
 		availableLines = 1
 	}
 
-	var allLines []string
-
-	if m.selectedLine.IsValid {
-		// Pretty print the JSON with syntax highlighting
-		prettyJSON, err := json.MarshalIndent(m.selectedLine.JSONData, "", "  ")
-		if err != nil {
-			allLines = []string{"Error formatting JSON: " + err.Error()}
-		} else {
-			// Apply syntax highlighting using Chroma
-			highlightedJSON, err := highlightJSON(string(prettyJSON))
-			if err != nil {
-				// Fallback to non-highlighted JSON if highlighting fails
-				jsonLines := strings.Split(string(prettyJSON), "\n")
-				for _, line := range jsonLines {
-					wrappedLines := m.wrapLine(line, m.width-2)
-					allLines = append(allLines, wrappedLines...)
-				}
-			} else {
-				// Split the highlighted JSON into lines and wrap long lines
-				jsonLines := strings.Split(highlightedJSON, "\n")
-				for _, line := range jsonLines {
-					wrappedLines := m.wrapLine(line, m.width-2)
-					allLines = append(allLines, wrappedLines...)
-				}
-			}
-		}
-	} else {
-		allLines = append(allLines, "Invalid JSON:")
-		// Wrap the raw line as well
-		wrappedRaw := m.wrapLine(m.selectedLine.RawLine, m.width-2)
-		allLines = append(allLines, wrappedRaw...)
-	}
+	allLines, _ := m.prettyLines()
 
 	// Apply scrolling - ensure we don't scroll past the content
 	maxScroll := len(allLines) - availableLines
@@ -1322,9 +1916,15 @@ func (m Model) renderPrettyView() string {
 	}
 
 	statusText := fmt.Sprintf(
-		"Pretty Print - Line %s%s | ↑/↓/PgUp/PgDn to scroll | ENTER/SPACE/ESC to return | q to quit",
-		humanize.Comma(int64(m.selectedLine.LineNumber)), scrollInfo,
+		"Pretty Print - Line %s%s%s | ↑/↓/PgUp/PgDn to scroll | ENTER/SPACE/ESC to return | q to quit",
+		humanize.Comma(int64(m.selectedLine.LineNumber)), scrollInfo, m.searchStatus(),
 	)
+	if tree := m.tree(); tree != nil {
+		statusText = fmt.Sprintf(
+			"Pretty Print - Line %s%s | %s%s | ←/→ collapse/expand | E/C all | =/!/e/s filter | %% stats | ESC to return",
+			humanize.Comma(int64(m.selectedLine.LineNumber)), scrollInfo, tree.cursor.path(), m.searchStatus(),
+		)
+	}
 	status := statusStyle.Width(m.width - 1).Render(statusText)
 	s.WriteString(status)
 
@@ -1343,47 +1943,7 @@ func (m Model) renderHelpView() string {
 	}
 
 	// Help content
-	helpLines := []string{
-		"SIFT - Interactive Log Viewer",
-		"",
-		"NAVIGATION:",
-		"  ↑/↓, k/j        Navigate up/down through log lines",
-		"  ←/→             Scroll selected line horizontally",
-		"  Ctrl+←/→        Fast horizontal scroll (5 characters)",
-		"  PgUp/PgDn       Page up/down through logs",
-		"  Home            Jump to first line",
-		"  End             Jump to last line (loads entire file if needed)",
-		"  Space/Enter     Open pretty-print view for selected line",
-		"",
-		"FILTERING:",
-		"  f               Add a new JQ filter",
-		"  F               Open Filter Management",
-		"    ↑/↓           Navigate between filters",
-		"    Space/Enter   Toggle filter on/off",
-		"    e             Edit filter expression",
-		"    d/x           Delete filter",
-		"    F/Esc         Exit management",
-		"",
-		"VIEW TRANSFORMATIONS:",
-		"  v/V             Enter View mode to transform display",
-		"                  (use JQ expressions to format output)",
-		"",
-		"TAIL MODE:",
-		"  t               Toggle Tail Mode (auto-jump to bottom on new lines)",
-		"                  Shows T=on/T=off in status bar",
-		"",
-		"OTHER:",
-		"  h               Show/hide this help screen",
-		"  q/Ctrl+C        Quit application",
-		"  Esc             Close help/pretty-print view or quit",
-		"",
-		"COMMAND LINE:",
-		"  -f <filter>     Apply JQ filter on startup",
-		"  -V <view>       Apply view transformation on startup",
-		"  -t              Start with Tail Mode enabled",
-		"",
-		"Press 'h' or 'Esc' to close this help screen",
-	}
+	helpLines := helpContent()
 
 	// Apply viewport scrolling
 	startLine := m.helpViewport
@@ -1457,14 +2017,24 @@ func (m Model) renderFilterManageView() string {
 			line := fmt.Sprintf("%s%s %s", prefix, status, filter.Expression)
 
 			// Truncate if too long
-			if len(line) > m.width-2 {
-				line = line[:m.width-5] + "..."
-			}
+			line = truncateText(line, m.width-2)
 
 			s.WriteString(style.Render(line))
 			s.WriteString("\n")
 			contentLines++
 
+			// Show the lines it failed on, with the first as an example
+			if count, sample, err := filter.failures.summary(); count > 0 && contentLines+2 <= availableLines {
+				for _, detail := range []string{
+					fmt.Sprintf("      %s lines failed: %v", humanize.Comma(int64(count)), err),
+					fmt.Sprintf("      e.g. line %d: %s", sample.LineNumber, sample.RawLine),
+				} {
+					s.WriteString(filterErrorStyle.Render(truncateText(detail, m.width-2)))
+					s.WriteString("\n")
+					contentLines++
+				}
+			}
+
 			if contentLines >= availableLines {
 				break
 			}
@@ -1482,7 +2052,8 @@ func (m Model) renderFilterManageView() string {
 	if m.filterEditMode {
 		// Create the complete filter edit bar content
 		filterEditPrefix := "Edit Filter: "
-		completeContent := filterEditPrefix + m.filterEditInput
+		completeContent := filterEditPrefix + m.filterEditInput + m.filterInputHint()
+		background, foreground := m.filterInputColors("#FF6600", "#FFFFFF")
 
 		// Calculate padding needed to fill the entire width (reserve rightmost column)
 		contentLen := len(completeContent)
@@ -1500,11 +2071,11 @@ func (m Model) renderFilterManageView() string {
 			afterCursor := completeContent[prefixLen+len(m.filterEditInput):]
 
 			normalStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FF6600")).
-				Foreground(lipgloss.Color("#FFFFFF"))
+				Background(lipgloss.Color(background)).
+				Foreground(lipgloss.Color(foreground))
 			cursorStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FFFFFF")).
-				Foreground(lipgloss.Color("#FF6600"))
+				Background(lipgloss.Color(foreground)).
+				Foreground(lipgloss.Color(background))
 
 			if len(afterCursor) > 0 {
 				styledContent = normalStyle.Render(beforeCursor) + cursorStyle.Render(string(afterCursor[0])) + normalStyle.Render(afterCursor[1:])
@@ -1518,11 +2089,11 @@ func (m Model) renderFilterManageView() string {
 			afterCursor := completeContent[prefixLen+m.filterEditCursorPos+1:]
 
 			normalStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FF6600")).
-				Foreground(lipgloss.Color("#FFFFFF"))
+				Background(lipgloss.Color(background)).
+				Foreground(lipgloss.Color(foreground))
 			cursorStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("#FFFFFF")).
-				Foreground(lipgloss.Color("#FF6600"))
+				Background(lipgloss.Color(foreground)).
+				Foreground(lipgloss.Color(background))
 
 			styledContent = normalStyle.Render(beforeCursor) + cursorStyle.Render(cursorChar) + normalStyle.Render(afterCursor)
 		}
@@ -1553,70 +2124,9 @@ func (m Model) renderFilterManageView() string {
 	return s.String()
 }
 
-// calculatePrettyMaxScroll calculates the maximum scroll position for pretty print view
-func (m Model) calculatePrettyMaxScroll() int {
-	if !m.showPretty || m.selectedLine == nil {
-		return 0
-	}
-
-	statusLines := 1
-	availableLines := m.height - statusLines
-	if availableLines < 1 {
-		availableLines = 1
-	}
-
-	var allLines []string
-
-	if m.selectedLine.IsValid {
-		prettyJSON, err := json.MarshalIndent(m.selectedLine.JSONData, "", "  ")
-		if err != nil {
-			allLines = []string{"Error formatting JSON: " + err.Error()}
-		} else {
-			// Apply syntax highlighting using Chroma
-			highlightedJSON, err := highlightJSON(string(prettyJSON))
-			if err != nil {
-				// Fallback to non-highlighted JSON if highlighting fails
-				jsonLines := strings.Split(string(prettyJSON), "\n")
-				for _, line := range jsonLines {
-					wrappedLines := m.wrapLine(line, m.width-2)
-					allLines = append(allLines, wrappedLines...)
-				}
-			} else {
-				// Split the highlighted JSON into lines and wrap long lines
-				jsonLines := strings.Split(highlightedJSON, "\n")
-				for _, line := range jsonLines {
-					wrappedLines := m.wrapLine(line, m.width-2)
-					allLines = append(allLines, wrappedLines...)
-				}
-			}
-		}
-	} else {
-		allLines = append(allLines, "Invalid JSON:")
-		wrappedRaw := m.wrapLine(m.selectedLine.RawLine, m.width-2)
-		allLines = append(allLines, wrappedRaw...)
-	}
-
-	maxScroll := len(allLines) - availableLines
-	if maxScroll < 0 {
-		maxScroll = 0
-	}
-	return maxScroll
-}
-
-// calculateHelpMaxScroll calculates the maximum scroll position for help view
-func (m Model) calculateHelpMaxScroll() int {
-	if !m.showHelp {
-		return 0
-	}
-
-	statusLines := 1
-	availableLines := m.height - statusLines
-	if availableLines < 1 {
-		availableLines = 1
-	}
-
-	// Help content lines (same as in renderHelpView)
-	helpLines := []string{
+// helpContent returns the lines shown on the help screen
+func helpContent() []string {
+	return []string{
 		"SIFT - Interactive Log Viewer",
 		"",
 		"NAVIGATION:",
@@ -1625,11 +2135,26 @@ func (m Model) calculateHelpMaxScroll() int {
 		"  Ctrl+←/→        Fast horizontal scroll (5 characters)",
 		"  PgUp/PgDn       Page up/down through logs",
 		"  Home            Jump to first line",
-		"  End             Jump to last line (loads entire file if needed)",
+		"  End             Jump to last line",
+		"  :               Go to line number",
 		"  Space/Enter     Open pretty-print view for selected line",
 		"",
+		"PRETTY-PRINT VIEW:",
+		"  ↑/↓, k/j        Move between values (the jq path is in the status bar)",
+		"  ←/→             Collapse/expand, or go to the parent/first child",
+		"  Tab/Shift+Tab   Next/previous key or item of the same parent",
+		"  E/C             Expand/collapse everything",
+		"  =/!             Filter lines where this field is/isn't this value",
+		"  e               Filter lines where this field is present",
+		"  s               Show only lines sharing this value, other filters off",
+		"  %               Show stats of this field",
+		"",
 		"FILTERING:",
 		"  f               Add a new JQ filter",
+		"                  ($source is the file name when merging files,",
+		"                  $raw the line as read, JSON or not)",
+		"  i               Filter lines that aren't JSON by their raw text,",
+		"                  show them always, or hide them (I= in status bar)",
 		"  F               Open Filter Management",
 		"    ↑/↓           Navigate between filters",
 		"    Space/Enter   Toggle filter on/off",
@@ -1637,10 +2162,42 @@ func (m Model) calculateHelpMaxScroll() int {
 		"    d/x           Delete filter",
 		"    F/Esc         Exit management",
 		"",
+		"SEARCH:",
+		"  /               Search forwards for text (Ctrl+R to use a regex)",
+		"  ?               Search backwards",
+		"                  (Enter on an empty search repeats the last one)",
+		"  n/N             Next/previous match, in pretty-print view too",
+		"",
+		"CONTEXT:",
+		"  +/-             Show more/fewer lines around each filter match",
+		"                  Shows C=N in status bar",
+		"",
 		"VIEW TRANSFORMATIONS:",
 		"  v/V             Enter View mode to transform display",
 		"                  (use JQ expressions to format output)",
 		"",
+		"COLUMNS:",
+		"  c               Pick fields of the line under the cursor to show as columns",
+		"    Space/Enter   Add/remove a column",
+		"    K/J           Move a column left/right",
+		"    </>           Make a column narrower/wider",
+		"    c/Esc         Exit the picker",
+		"  ←/→             Scroll the table a column at a time",
+		"",
+		"FIELD BROWSER:",
+		"  b               List the fields of the lines in memory, their types,",
+		"                  how many lines have them and how many values they have",
+		"    c             Add the field as a column",
+		"    f             Filter on the field",
+		"    s             Show stats of the field",
+		"    t/Enter       Show/hide the field's most common values",
+		"    b/Esc         Close the browser",
+		"",
+		"STATS:",
+		"  %               Show/hide stats of the last field, next to the lines",
+		"                  (s in the field browser or % in the pretty view picks one)",
+		"  Esc             Close the stats",
+		"",
 		"TAIL MODE:",
 		"  t               Toggle Tail Mode (auto-jump to bottom on new lines)",
 		"                  Shows T=on/T=off in status bar",
@@ -1654,9 +2211,76 @@ func (m Model) calculateHelpMaxScroll() int {
 		"  -f <filter>     Apply JQ filter on startup",
 		"  -V <view>       Apply view transformation on startup",
 		"  -t              Start with Tail Mode enabled",
+		"  -C <n>          Show n lines around each filter match",
+		"  -invalid <mode> Lines that aren't JSON: filter, show or hide (default filter)",
+		"  -columns <list> Show fields as columns, e.g. ts,level,msg (name:width sets a width)",
+		"  -               Read logs from stdin (default when input is piped)",
+		"  a.log b.log     Merge several files, ordered by timestamp",
+		"  -ts <path>      Timestamp field used to merge files (default .timestamp)",
+		"  -max-line-size  Truncate lines longer than this (default 1.0 MiB)",
+		"  -poll           Poll for new lines instead of using file events",
+		"  -poll-interval  How often to poll for new lines (default 200ms)",
+		"  -partial-wait   Wait this long for the rest of a half written line (default 2s)",
+		"  -index-cache    Keep the line index in a .siftidx file next to the log",
+		"  -max-lines      Most lines kept in memory (default unlimited)",
+		"  -max-memory     Rough memory budget for lines, e.g. 512MiB (default unlimited)",
 		"",
 		"Press 'h' or 'Esc' to close this help screen",
 	}
+}
+
+// invalidLineHeader returns the heading shown above a line that couldn't be pretty printed
+func invalidLineHeader(line *LogLine) string {
+	if line.Marker {
+		return "Marker inserted by sift, not part of the file:"
+	}
+	if line.Truncated {
+		return fmt.Sprintf("Line truncated to %s (-max-line-size):", humanize.IBytes(uint64(len(line.RawLine))))
+	}
+	return "Invalid JSON:"
+}
+
+// calculatePrettyMaxScroll calculates the maximum scroll position for pretty print view
+func (m Model) calculatePrettyMaxScroll() int {
+	if !m.showPretty || m.selectedLine == nil {
+		return 0
+	}
+
+	statusLines := 1
+	availableLines := m.height - statusLines
+	if availableLines < 1 {
+		availableLines = 1
+	}
+
+	allLines, _ := m.prettyLines()
+
+	maxScroll := len(allLines) - availableLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	return maxScroll
+}
+
+// prettyLines returns the selected line pretty printed and wrapped to the
+// screen, with search matches highlighted, and which of them have matches
+func (m Model) prettyLines() (allLines []string, matchLines []int) {
+	layout := m.prettyLayout()
+	return layout.lines, layout.matchLines
+}
+
+// calculateHelpMaxScroll calculates the maximum scroll position for help view
+func (m Model) calculateHelpMaxScroll() int {
+	if !m.showHelp {
+		return 0
+	}
+
+	statusLines := 1
+	availableLines := m.height - statusLines
+	if availableLines < 1 {
+		availableLines = 1
+	}
+
+	helpLines := helpContent()
 
 	maxScroll := len(helpLines) - availableLines
 	if maxScroll < 0 {
@@ -1753,37 +2377,32 @@ func getClipboardText() string {
 	return text
 }
 
-// loadInitialChunk loads the first chunk of lines from the log file
-func loadInitialChunk(filename string, chunkSize int) ([]LogLine, *os.File, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, nil, err
+// parseLogLine builds a LogLine from a raw line, parsing it as JSON if possible
+func parseLogLine(lineNumber int, rawLine string) LogLine {
+	logLine := LogLine{
+		LineNumber: lineNumber,
+		RawLine:    rawLine,
+		IsValid:    false,
 	}
 
-	var lines []LogLine
-	scanner := bufio.NewScanner(file)
-	lineNumber := 1
-
-	for scanner.Scan() && lineNumber <= chunkSize {
-		rawLine := scanner.Text()
-		logLine := LogLine{
-			LineNumber: lineNumber,
-			RawLine:    rawLine,
-			IsValid:    false,
-		}
+	// Try to parse as JSON
+	if jsonData, ok := decodeJSONLine(rawLine); ok {
+		logLine.JSONData = jsonData
+		logLine.IsValid = true
+	}
 
-		// Try to parse as JSON
-		var jsonData map[string]interface{}
-		if err := json.Unmarshal([]byte(rawLine), &jsonData); err == nil {
-			logLine.JSONData = jsonData
-			logLine.IsValid = true
-		}
+	return logLine
+}
 
-		lines = append(lines, logLine)
-		lineNumber++
+// loadInitialChunk loads the first chunk of lines from the log file
+func loadInitialChunk(filename string, chunkSize int) ([]LogLine, *logFile, error) {
+	file, err := openLogFile(filename)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := scanner.Err(); err != nil {
+	lines, _, err := file.readLines(1, chunkSize)
+	if err != nil {
 		file.Close()
 		return nil, nil, err
 	}
@@ -1797,39 +2416,16 @@ func (m *Model) loadMoreLines(chunkSize int) error {
 		return nil
 	}
 
-	scanner := bufio.NewScanner(m.file)
-	linesLoaded := 0
-	nextLineNumber := len(m.lines) + 1
-
-	for scanner.Scan() && linesLoaded < chunkSize {
-		rawLine := scanner.Text()
-		logLine := LogLine{
-			LineNumber: nextLineNumber,
-			RawLine:    rawLine,
-			IsValid:    false,
-		}
-
-		// Try to parse as JSON
-		var jsonData map[string]interface{}
-		if err := json.Unmarshal([]byte(rawLine), &jsonData); err == nil {
-			logLine.JSONData = jsonData
-			logLine.IsValid = true
-		}
-
-		m.lines = append(m.lines, logLine)
-		nextLineNumber++
-		linesLoaded++
-	}
-
-	if err := scanner.Err(); err != nil {
+	newLines, atEOF, err := m.file.readLines(m.lastLineNum+1, chunkSize)
+	m.lines = append(m.lines, newLines...)
+	if err != nil {
 		return err
 	}
 
 	// Check if we've reached the end of the file
-	if linesLoaded < chunkSize {
+	if atEOF {
 		m.isFileFullyLoaded = true
-		m.file.Close()
-		m.file = nil
+		m.closeLoadedFile()
 	}
 
 	// Update last line number
@@ -1840,38 +2436,51 @@ func (m *Model) loadMoreLines(chunkSize int) error {
 	return nil
 }
 
-// estimateTotalLines estimates the total number of lines in the file
-func estimateTotalLines(filename string, sampleSize int) (int, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return 0, err
+// lazyLoadChunkSize is how many lines are read each time scrolling nears the end of what's loaded
+const lazyLoadChunkSize = 500
+
+// loadMoreLinesCmd returns a command that reads the next chunk of lines for lazy loading
+func loadMoreLinesCmd(file *logFile, nextLineNumber int) tea.Cmd {
+	return func() tea.Msg {
+		if file == nil {
+			return loadMoreLinesMsg{isComplete: true}
+		}
+
+		newLines, atEOF, err := file.readLines(nextLineNumber, lazyLoadChunkSize)
+		return loadMoreLinesMsg{newLines: newLines, err: err, isComplete: atEOF}
 	}
-	defer file.Close()
+}
 
-	stat, err := file.Stat()
+// estimateTotalLines estimates the total number of lines in the file. It
+// returns 0 when the size of a compressed file's content isn't known.
+func estimateTotalLines(filename string, sampleSize int) (int, error) {
+	fileSize, ok, err := uncompressedSize(filename)
 	if err != nil {
 		return 0, err
 	}
-	fileSize := stat.Size()
 
-	if fileSize == 0 {
+	if !ok || fileSize == 0 {
 		return 0, nil
 	}
 
-	// Read a sample from the beginning
-	scanner := bufio.NewScanner(file)
-	var totalBytes int64
-	lineCount := 0
-
-	for scanner.Scan() && lineCount < sampleSize {
-		totalBytes += int64(len(scanner.Bytes()) + 1) // +1 for newline
-		lineCount++
+	file, err := openLogFile(filename)
+	if err != nil {
+		return 0, err
 	}
+	defer file.Close()
 
-	if err := scanner.Err(); err != nil {
+	// Read a sample from the beginning
+	sample, _, err := file.readLines(1, sampleSize)
+	if err != nil {
 		return 0, err
 	}
 
+	var totalBytes int64
+	lineCount := len(sample)
+	for _, line := range sample {
+		totalBytes += int64(len(line.RawLine) + 1) // +1 for newline
+	}
+
 	if lineCount == 0 {
 		return 0, nil
 	}
@@ -1883,68 +2492,50 @@ func estimateTotalLines(filename string, sampleSize int) (int, error) {
 	return estimatedLines, nil
 }
 
-// tickCmd returns a command that sends a tick message after a delay
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Millisecond*200, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
-}
-
-// checkForNewLines checks if the file has grown and returns new lines
-func checkForNewLines(filename string, currentSize int64, lastLineNum int) tea.Cmd {
+// checkForNewLines checks if the file has grown, or was rotated or truncated,
+// and returns any new lines. A message is returned even when nothing changed
+// so the model knows the check is done.
+func checkForNewLines(filename string, state followState, lastLineNum int) tea.Cmd {
 	return func() tea.Msg {
-		file, err := os.Open(filename)
-		if err != nil {
-			return nil
-		}
-		defer file.Close()
-
-		// Check if file has grown
-		stat, err := file.Stat()
-		if err != nil {
-			return nil
-		}
-
-		if stat.Size() <= currentSize {
-			return nil // No new content
-		}
+		newLines, state := readNewLines(filename, state, lastLineNum+1)
+		finished, newLines := splitFinished(newLines, state)
+		return followMsg{newLines: newLines, finished: finished, states: []followState{state}, firstLineNumber: lastLineNum + 1}
+	}
+}
 
-		// Seek to the previous end of file
-		_, err = file.Seek(currentSize, io.SeekStart)
-		if err != nil {
-			return nil
-		}
+// appendNewLines adds lines read while tailing or streaming to the model
+func (m *Model) appendNewLines(newLines []LogLine) {
+	if len(newLines) == 0 {
+		return
+	}
 
-		// Read new lines
-		var newLines []LogLine
-		scanner := bufio.NewScanner(file)
-		lineNumber := lastLineNum + 1
+	// Update state
+	m.lines = append(m.lines, newLines...)
+	m.lastLineNum = newLines[len(newLines)-1].LineNumber
 
-		for scanner.Scan() {
-			rawLine := scanner.Text()
-			logLine := LogLine{
-				LineNumber: lineNumber,
-				RawLine:    rawLine,
-				IsValid:    false,
-			}
+	// Only the new lines need to be checked against the filters
+	m.filterAppendedLines(newLines)
 
-			// Try to parse as JSON
-			var jsonData map[string]interface{}
-			if err := json.Unmarshal([]byte(rawLine), &jsonData); err == nil {
-				logLine.JSONData = jsonData
-				logLine.IsValid = true
+	// If tail mode is enabled, jump to the bottom automatically
+	// This must happen AFTER filters are applied
+	if m.tailMode {
+		visibleLines := m.getVisibleLines()
+		if len(visibleLines) > 0 {
+			m.cursor = len(visibleLines) - 1
+			// Adjust viewport to show the last line at the bottom
+			if m.cursor >= m.height-1 { // Account for status bar only
+				m.viewport = m.cursor - m.height + 2
+				if m.viewport < 0 {
+					m.viewport = 0
+				}
+			} else {
+				m.viewport = 0
 			}
-
-			newLines = append(newLines, logLine)
-			lineNumber++
-		}
-
-		if len(newLines) > 0 {
-			return newLinesMsg(newLines)
+			m.lineScrollOffset = 0
 		}
-
-		return nil
 	}
+
+	m.enforceMemoryBudget()
 }
 
 // restorePositionAfterFilter restores the cursor position after applying filters
@@ -1957,21 +2548,13 @@ func (m *Model) restorePositionAfterFilter(targetLineNumber int) {
 		return
 	}
 
-	// Find the best position to restore to
-	bestPosition := 0
-
-	// Look for the exact line number first
-	for i, line := range visibleLines {
-		if line.LineNumber == targetLineNumber {
-			bestPosition = i
-			break
-		}
-		// If we find a line number greater than target, use the previous position
-		if line.LineNumber > targetLineNumber {
-			break
-		}
-		// Keep track of the highest line number below target
-		bestPosition = i
+	// Find the exact line number, or the highest one below it. Visible
+	// lines are in order, and there may be millions of them.
+	bestPosition := sort.Search(len(visibleLines), func(i int) bool {
+		return visibleLines[i].LineNumber > targetLineNumber
+	})
+	if bestPosition > 0 {
+		bestPosition--
 	}
 
 	m.cursor = bestPosition
@@ -1995,20 +2578,51 @@ func (m Model) getVisibleLines() []LogLine {
 	if len(m.filters) == 0 {
 		return m.lines
 	}
+	if m.contextLines > 0 {
+		return m.linesWithContext()
+	}
 	return m.filteredLines
 }
 
-// addFilter adds a new JQ filter to the model
-func (m *Model) addFilter(expression string) error {
+// newFilter parses and compiles a JQ filter expression
+func newFilter(expression string) (Filter, error) {
 	query, err := gojq.Parse(expression)
 	if err != nil {
-		return err
+		return Filter{}, newExprError(expression, err)
+	}
+
+	code, err := gojq.Compile(query, gojq.WithVariables(filterVariables))
+	if err != nil {
+		return Filter{}, newExprError(expression, err)
 	}
 
-	filter := Filter{
+	return Filter{
 		Expression: expression,
 		Query:      query,
+		Code:       code,
 		Enabled:    true, // New filters are enabled by default
+		failures:   &filterErrors{},
+	}, nil
+}
+
+// run evaluates the filter against a line, binding filterVariables when
+// compiled. Lines that aren't JSON are given as their raw text.
+func (f Filter) run(line LogLine) gojq.Iter {
+	data := line.data()
+	if !line.IsValid {
+		data = invalidLineData(line)
+	}
+	if f.Code == nil {
+		return f.Query.Run(data)
+	}
+	return f.Code.Run(data, line.Source, line.RawLine)
+}
+
+// addFilter adds a new JQ filter to the model
+func (m *Model) addFilter(expression string) error {
+	filter, err := newFilter(expression)
+	if err != nil {
+		return err
 	}
 
 	m.filters = append(m.filters, filter)
@@ -2017,35 +2631,79 @@ func (m *Model) addFilter(expression string) error {
 
 // applyFilters applies all filters to the lines and updates filteredLines
 func (m *Model) applyFilters() {
+	if m.filterJob != nil {
+		return // Lines that changed meanwhile are filtered when the job finishes
+	}
 	if len(m.filters) == 0 {
 		m.filteredLines = m.lines
 		return
 	}
 
-	m.filteredLines = nil
-	for _, line := range m.lines {
-		if m.linePassesAllFilters(line) {
-			m.filteredLines = append(m.filteredLines, line)
-		}
+	resetFilterErrors(m.filters)
+	m.filteredLines = filterLines(m.filters, m.invalidLines, m.lines)
+}
+
+// filterAppendedLines adds those of newLines, just appended to m.lines, that
+// pass the filters to filteredLines, without checking earlier lines again
+func (m *Model) filterAppendedLines(newLines []LogLine) {
+	if m.filterJob != nil {
+		return // Lines appended meanwhile are filtered when the job finishes
+	}
+	if len(m.filters) == 0 {
+		m.filteredLines = m.lines
+		return
 	}
+	m.filteredLines = append(m.filteredLines, filterLines(m.filters, m.invalidLines, newLines)...)
+}
+
+// filterPrependedLines adds those of newLines, just loaded above m.lines,
+// that pass the filters to the start of filteredLines
+func (m *Model) filterPrependedLines(newLines []LogLine) {
+	if m.filterJob != nil {
+		return // The job starts over when it finds lines were loaded above
+	}
+	if len(m.filters) == 0 {
+		m.filteredLines = m.lines
+		return
+	}
+	m.filteredLines = append(filterLines(m.filters, m.invalidLines, newLines), m.filteredLines...)
 }
 
 // linePassesAllFilters checks if a line passes all active filters
 func (m Model) linePassesAllFilters(line LogLine) bool {
+	return linePassesFilters(m.filters, m.invalidLines, line)
+}
+
+// linePassesFilters checks if a line passes all enabled filters, invalid
+// saying how lines that aren't JSON are treated
+func linePassesFilters(filters []Filter, invalid invalidLineMode, line LogLine) bool {
+	if line.Marker {
+		return true // Markers explain gaps in what was read, keep them visible
+	}
 	if !line.IsValid {
-		return false // Invalid JSON never passes filters
+		switch invalid {
+		case invalidLinesShown:
+			return true
+		case invalidLinesHidden:
+			return false
+		}
 	}
 
-	for _, filter := range m.filters {
+	for _, filter := range filters {
 		if !filter.Enabled {
 			continue // Skip disabled filters
 		}
-		iter := filter.Query.Run(line.JSONData)
+		iter := filter.run(line)
 		result, ok := iter.Next()
 		if !ok {
 			return false // No result means filter failed
 		}
 		if err, ok := result.(error); ok && err != nil {
+			// Filters written for JSON lines often fail on the raw text of
+			// other lines, which isn't worth reporting
+			if line.IsValid {
+				filter.failures.record(line, err)
+			}
 			return false // Error means filter failed
 		}
 		// Check if result is truthy
@@ -2095,10 +2753,32 @@ func main() {
 	var viewExpression string
 	var showVersion bool
 	var tailMode bool
+	var timeField string
+	var maxLineSizeFlag string
+	var pollInterval time.Duration
+	var partialWait time.Duration
+	var forcePoll bool
+	var indexCache bool
+	var maxLines int
+	var maxMemoryFlag string
+	var contextLines int
+	var invalidFlag string
+	var columnsFlag string
 	flag.Var(&filters, "f", "JQ filter expression (can be used multiple times)")
 	flag.StringVar(&viewExpression, "V", "", "JQ view transformation expression")
 	flag.BoolVar(&showVersion, "v", false, "Show version and exit")
 	flag.BoolVar(&tailMode, "t", false, "Start with Tail Mode enabled (auto-jump to bottom on new lines)")
+	flag.StringVar(&timeField, "ts", defaultTimeField, "JQ path of the timestamp used to interleave multiple files")
+	flag.StringVar(&maxLineSizeFlag, "max-line-size", humanize.IBytes(defaultMaxLineSize), "Longest line kept in full, longer lines are truncated (0 for unlimited)")
+	flag.DurationVar(&pollInterval, "poll-interval", defaultPollInterval, "How often files are checked for new lines when polling")
+	flag.BoolVar(&forcePoll, "poll", false, "Poll for new lines instead of using file events (e.g. on NFS)")
+	flag.DurationVar(&partialWait, "partial-wait", defaultPartialLineTimeout, "How long to wait for the rest of a half written line before showing it (0 to show it immediately)")
+	flag.BoolVar(&indexCache, "index-cache", false, "Save the line index next to the log file so reopening it is instant")
+	flag.IntVar(&maxLines, "max-lines", 0, "Most lines kept in memory, farther lines are dropped (0 for unlimited)")
+	flag.StringVar(&maxMemoryFlag, "max-memory", "0", "Rough memory budget for lines, e.g. 512MiB (0 for unlimited)")
+	flag.IntVar(&contextLines, "C", 0, "Lines shown before and after each filter match, like grep -C")
+	flag.StringVar(&invalidFlag, "invalid", invalidLinesFiltered.String(), "How filters treat lines that aren't JSON: filter (by their raw text), show or hide")
+	flag.StringVar(&columnsFlag, "columns", "", "Fields shown as aligned columns, e.g. ts,level,msg or .request.method:10 to set a width")
 	flag.Parse()
 
 	// Handle version flag
@@ -2108,25 +2788,91 @@ func main() {
 		return
 	}
 
+	// Parse the line size limit before any file is read
+	size, err := humanize.ParseBytes(maxLineSizeFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing max line size '%s': %v\n", maxLineSizeFlag, err)
+		os.Exit(1)
+	}
+	maxLineSize = int(size)
+	partialLineTimeout = partialWait
+
+	maxMemory, err := humanize.ParseBytes(maxMemoryFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing max memory '%s': %v\n", maxMemoryFlag, err)
+		os.Exit(1)
+	}
+
+	invalidLines, err := parseInvalidLineMode(invalidFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing invalid '%s': %v\n", invalidFlag, err)
+		os.Exit(1)
+	}
+
 	args := flag.Args()
-	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <log-file>\n", os.Args[0])
+	if len(args) < 1 && !isStdinPiped() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <log-file> [<log-file>...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       <command> | %s [options] [-]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	filename := args[0]
+	// Read from stdin when no file is given and input is piped
+	filename := "-"
+	if len(args) > 0 {
+		filename = args[0]
+	}
 
-	// Check if file exists and get initial file size before any reads
-	stat, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: File '%s' does not exist\n", filename)
-		os.Exit(1)
+	// Stdin and named pipes are streamed instead of lazily loaded, and
+	// several files are merged into one view ordered by timestamp
+	var stream *lineStream
+	var sources []logSource
+	var timeQuery *gojq.Code
+	var follow followState
+	var compression compressionType
+	displayName := filename
+	if len(args) > 1 {
+		var err error
+		timeQuery, err = compileTimeField(timeField)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing timestamp field '%s': %v\n", timeField, err)
+			os.Exit(1)
+		}
+
+		sources = newLogSources(args)
+		for i := range sources {
+			if isStreamSource(sources[i].filename) {
+				fmt.Fprintf(os.Stderr, "Error: '%s' is a stream and can't be merged with other files\n", sources[i].filename)
+				os.Exit(1)
+			}
+			sources[i].follow = newFollowState(statLogFile(sources[i].filename))
+		}
+		displayName = fmt.Sprintf("%d files", len(sources))
+	} else if isStreamSource(filename) {
+		stream = startLineStream(filename)
+		displayName = streamDisplayName(filename)
+	} else {
+		follow = newFollowState(statLogFile(filename))
+
+		var err error
+		compression, err = detectFileCompression(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			os.Exit(1)
+		}
 	}
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting file info: %v\n", err)
-		os.Exit(1)
+
+	// Watch files for new lines, compressed files are archives that aren't appended to
+	var watcher fileWatcher
+	if len(sources) > 0 {
+		filenames := make([]string, len(sources))
+		for i, source := range sources {
+			filenames[i] = source.filename
+		}
+		watcher = newFileWatcher(filenames, pollInterval, forcePoll)
+	} else if stream == nil && compression == compressionNone {
+		watcher = newFileWatcher([]string{filename}, pollInterval, forcePoll)
 	}
 
 	// Constants for lazy loading
@@ -2134,11 +2880,37 @@ func main() {
 	const sampleSize = 100        // Sample size for estimating total lines
 
 	var lines []LogLine
-	var file *os.File
+	var file *logFile
 	var isFileFullyLoaded bool
+	var approxLineNumbers bool
 
-	if tailMode {
-		// Load entire file when tail mode is enabled
+	if stream != nil {
+		// Lines arrive through the stream once the TUI starts
+		isFileFullyLoaded = true
+	} else if len(sources) > 0 {
+		// Merging needs every line, so multiple files are always loaded in full
+		mergedLines, err := loadMergedLines(sources, timeQuery)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
+			os.Exit(1)
+		}
+		lines = mergedLines
+		isFileFullyLoaded = true
+	} else if tailMode && compression == compressionNone {
+		// Read the end backwards instead of loading the whole file, the
+		// index numbers the lines exactly once it's built
+		tailLines, approx, tailFile, err := loadTailWindow(filename, tailWindowLines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
+			os.Exit(1)
+		}
+		lines = tailLines
+		approxLineNumbers = approx
+		follow.continueFrom(tailFile) // Tail from where reading stopped
+		tailFile.Close()
+		isFileFullyLoaded = true
+	} else if tailMode {
+		// Compressed files can't be read backwards, load them entirely
 		allLines, err := loadAllLines(filename)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
@@ -2156,6 +2928,11 @@ func main() {
 			os.Exit(1)
 		}
 		isFileFullyLoaded = len(lines) < initialChunkSize
+		if isFileFullyLoaded {
+			follow.continueFrom(file) // Tail from where reading stopped
+			file.Close()
+			file = nil
+		}
 	}
 
 	// Estimate total lines in the file (only needed if not fully loaded)
@@ -2177,7 +2954,7 @@ func main() {
 
 	// Initialize the model
 	m := Model{
-		filename:            filename,
+		filename:            displayName,
 		lines:               lines,
 		filteredLines:       lines, // Initialize with all lines
 		filters:             []Filter{},
@@ -2185,7 +2962,7 @@ func main() {
 		viewport:            0,
 		height:              24, // Default height
 		width:               80, // Default width
-		fileSize:            stat.Size(),
+		follow:              follow,
 		lastLineNum:         lastLineNum,
 		filterMode:          false,
 		filterInput:         "",
@@ -2197,9 +2974,23 @@ func main() {
 		isFileFullyLoaded:   isFileFullyLoaded,
 		loadingMoreLines:    false,
 		estimatedTotalLines: estimatedTotal,
+		compression:         compression,
+		stream:              stream,
+		watcher:             watcher,
+		sources:             sources,
+		timeQuery:           timeQuery,
 		showSpinner:         false,
 		spinnerFrame:        0,
 		tailMode:            tailMode, // Set tail mode from command line flag
+		// Index files too big to load at once so any line can be reached
+		// quickly, and to number lines read backwards from the end
+		indexing:          (!isFileFullyLoaded || approxLineNumbers) && stream == nil && len(sources) == 0 && compression == compressionNone,
+		indexCache:        indexCache,
+		approxLineNumbers: approxLineNumbers,
+		budget:            memoryBudget{maxLines: maxLines, maxBytes: int64(maxMemory)},
+		contextLines:      max(0, min(contextLines, maxContextLines)),
+		contextCache:      &contextCache{},
+		invalidLines:      invalidLines,
 	}
 
 	// Add command-line filters
@@ -2210,14 +3001,16 @@ func main() {
 		}
 	}
 
-	// Apply filters if any were provided
+	// Filter in the background if any filters were provided, Init waits for
+	// the results
 	if len(filters) > 0 {
-		m.applyFilters()
+		m.refilter(nil)
 	}
+	m.enforceMemoryBudget()
 
 	// Apply view transformation if provided
 	if viewExpression != "" {
-		query, err := gojq.Parse(viewExpression)
+		query, err := parseViewExpression(viewExpression)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing view expression '%s': %v\n", viewExpression, err)
 			os.Exit(1)
@@ -2226,26 +3019,66 @@ func main() {
 		m.viewExpression = viewExpression
 	}
 
+	// Show the lines as a table if columns were given
+	if columnsFlag != "" {
+		columns, err := parseColumns(columnsFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing columns '%s': %v\n", columnsFlag, err)
+			os.Exit(1)
+		}
+		m.setColumns(columns)
+	}
+
 	// If tail mode is enabled, mark that we need to jump to end once window size is known
 	if tailMode {
 		m.tailMode = true
 		m.needsInitialTailJump = true
 	}
 
-	// Start the TUI
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	// Start the TUI, reading keys from the terminal when stdin carries the log
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if filename == "-" {
+		opts = append(opts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(m, opts...)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// statLogFile checks that a log file exists and returns its initial size
+// before any reads, exiting with an error message otherwise
+func statLogFile(filename string) os.FileInfo {
+	stat, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: File '%s' does not exist\n", filename)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting file info: %v\n", err)
+		os.Exit(1)
+	}
+	return stat
+}
+
 // cleanup closes any open file handles
 func (m *Model) cleanup() {
+	m.stopFilterJob()
+	m.preview.counting.stop()
+	m.stopSchemaJob()
+	m.stats.computing.stop()
+	if m.searching.running() {
+		m.cancelSearch()
+	}
 	if m.file != nil {
 		m.file.Close()
 		m.file = nil
 	}
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
 }
 
 // getSpinnerChar returns the current spinner character
@@ -2262,124 +3095,42 @@ func spinnerTickCmd() tea.Cmd {
 }
 
 // loadToEndCmd loads all remaining lines from a file in chunks
-func loadToEndCmd(filename string, file *os.File, currentLineCount int) tea.Cmd {
+func loadToEndCmd(filename string, file *logFile, index *lineIndex, lastLineNum int) tea.Cmd {
 	return func() tea.Msg {
 		// If file handle is nil, we need to reopen and seek to the correct position
-		var f *os.File
-		var err error
-		shouldClose := false
-
-		if file != nil {
-			f = file
-		} else {
-			// No file handle, open fresh and seek to correct position
-			f, err = os.Open(filename)
+		f := file
+		if f == nil {
+			var err error
+			f, err = reopenLogFile(filename, index, lastLineNum+1)
 			if err != nil {
 				return loadToEndMsg{err: err, isComplete: true}
 			}
-			shouldClose = true
-
-			// Skip lines we've already read by scanning through them
-			scanner := bufio.NewScanner(f)
-			for i := 0; i < currentLineCount && scanner.Scan(); i++ {
-				// Skip already read lines
-			}
-			if err := scanner.Err(); err != nil {
-				if shouldClose {
-					f.Close()
-				}
-				return loadToEndMsg{err: err, isComplete: true}
-			}
-		}
-
-		if shouldClose {
-			defer f.Close()
 		}
 
-		// Load remaining lines in chunks
-		// The file position should already be correct if we have a file handle
-		scanner := bufio.NewScanner(f)
-		lineNumber := currentLineCount + 1
-		var allNewLines []LogLine
+		// Load the next chunk of remaining lines, handing the file back so
+		// the next chunk continues from here
 		const chunkSize = 1000
+		newLines, atEOF, err := f.readLines(lastLineNum+1, chunkSize)
 
-		for scanner.Scan() {
-			rawLine := scanner.Text()
-			logLine := LogLine{
-				LineNumber: lineNumber,
-				RawLine:    rawLine,
-				IsValid:    false,
-			}
-
-			// Try to parse as JSON
-			var jsonData map[string]interface{}
-			if err := json.Unmarshal([]byte(rawLine), &jsonData); err == nil {
-				logLine.JSONData = jsonData
-				logLine.IsValid = true
-			}
-
-			allNewLines = append(allNewLines, logLine)
-			lineNumber++
-
-			// Send chunk if we've loaded enough lines
-			if len(allNewLines) >= chunkSize {
-				return loadToEndMsg{
-					newLines:   allNewLines,
-					err:        nil,
-					isComplete: false,
-				}
-			}
-		}
-
-		if err := scanner.Err(); err != nil {
-			return loadToEndMsg{
-				newLines:   allNewLines,
-				err:        err,
-				isComplete: true,
-			}
-		}
-
-		// Return final chunk
 		return loadToEndMsg{
-			newLines:   allNewLines,
-			err:        nil,
-			isComplete: true,
+			newLines:   newLines,
+			file:       f,
+			err:        err,
+			isComplete: atEOF,
 		}
 	}
 }
 
 // loadAllLines loads all lines from the file (used when tail mode is enabled)
 func loadAllLines(filename string) ([]LogLine, error) {
-	file, err := os.Open(filename)
+	file, err := openLogFile(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var lines []LogLine
-	scanner := bufio.NewScanner(file)
-	lineNumber := 1
-
-	for scanner.Scan() {
-		rawLine := scanner.Text()
-		logLine := LogLine{
-			LineNumber: lineNumber,
-			RawLine:    rawLine,
-			IsValid:    false,
-		}
-
-		// Try to parse as JSON
-		var jsonData map[string]interface{}
-		if err := json.Unmarshal([]byte(rawLine), &jsonData); err == nil {
-			logLine.JSONData = jsonData
-			logLine.IsValid = true
-		}
-
-		lines = append(lines, logLine)
-		lineNumber++
-	}
-
-	if err := scanner.Err(); err != nil {
+	lines, _, err := file.readLines(1, 0)
+	if err != nil {
 		return nil, err
 	}
 
@@ -2387,51 +3138,64 @@ func loadAllLines(filename string) ([]LogLine, error) {
 }
 
 // applyViewTransform applies the view transformation filter to JSON data
-func (m Model) applyViewTransform(jsonData map[string]interface{}) string {
-	if m.viewFilter == nil {
-		return ""
+func (m Model) applyViewTransform(jsonData interface{}) string {
+	transformed, _ := m.viewTransform(jsonData)
+	return transformed
+}
+
+// viewTransform applies the view transformation filter to JSON data,
+// returning why it failed when it did
+func (m Model) viewTransform(jsonData interface{}) (string, error) {
+	return transformLine(m.viewFilter, jsonData)
+}
+
+// transformLine applies a view transformation to JSON data, returning why it
+// failed when it did
+func transformLine(viewFilter *gojq.Query, jsonData interface{}) (transformed string, err error) {
+	if viewFilter == nil {
+		return "", nil
 	}
 
 	// Safely run the filter with error handling
 	defer func() {
 		if r := recover(); r != nil {
 			// If panic occurs, return empty string to fall back to original
-			return
+			transformed, err = "", fmt.Errorf("panic: %v", r)
 		}
 	}()
 
-	iter := m.viewFilter.Run(jsonData)
+	iter := viewFilter.Run(jsonData)
 	result, ok := iter.Next()
 	if !ok {
-		return "" // No result, fall back to original
+		return "", nil // No result, fall back to original
 	}
 
 	// Handle errors
 	if err, ok := result.(error); ok && err != nil {
-		return "" // Error occurred, fall back to original
+		return "", err // Error occurred, fall back to original
 	}
 
 	// Convert result to string representation
 	switch v := result.(type) {
 	case string:
-		return v
+		return v, nil
 	case nil:
-		return "null"
+		return "null", nil
 	case bool:
 		if v {
-			return "true"
+			return "true", nil
 		}
-		return "false"
+		return "false", nil
 	case int:
-		return fmt.Sprintf("%d", v)
+		return fmt.Sprintf("%d", v), nil
 	case float64:
-		return fmt.Sprintf("%g", v)
+		return fmt.Sprintf("%g", v), nil
 	default:
 		// For complex objects, marshal to JSON
 		if jsonBytes, err := json.Marshal(v); err == nil {
-			return string(jsonBytes)
+			return string(jsonBytes), nil
 		}
 		// If marshalling fails, use string representation
-		return fmt.Sprintf("%v", v)
+		return fmt.Sprintf("%v", v), nil
 	}
 }
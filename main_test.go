@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/itchyny/gojq"
@@ -202,10 +203,11 @@ func TestGetSpinnerChar(t *testing.T) {
 
 // TestModelInit tests the Model.Init method
 func TestModelInit(t *testing.T) {
-	model := Model{}
+	model := Model{watcher: newPollWatcher(time.Hour)}
+	defer model.watcher.Close()
 	cmd := model.Init()
 
-	// Init should return a tick command
+	// Init should wait for the file to change
 	if cmd == nil {
 		t.Error("Init should return a command")
 	}
@@ -489,8 +491,8 @@ func TestCalculateHelpMaxScroll(t *testing.T) {
 func TestMessageHandling(t *testing.T) {
 	model := Model{width: 80, height: 24}
 
-	// Test tick message
-	newModel, _ := model.Update(tickMsg{})
+	// Test file event message
+	newModel, _ := model.Update(fileEventMsg{})
 	_ = newModel // Just ensure no panic
 
 	// Test help toggle
@@ -501,11 +503,20 @@ func TestMessageHandling(t *testing.T) {
 	}
 }
 
-// TestTickCmd tests the tick command
-func TestTickCmd(t *testing.T) {
-	cmd := tickCmd()
+// TestWaitForFileEvent tests the file event command
+func TestWaitForFileEvent(t *testing.T) {
+	if cmd := waitForFileEvent(nil, 0); cmd != nil {
+		t.Error("waitForFileEvent should return nil without a watcher")
+	}
+
+	w := newPollWatcher(time.Millisecond)
+	defer w.Close()
+	cmd := waitForFileEvent(w, 0)
 	if cmd == nil {
-		t.Error("tickCmd should return a command")
+		t.Fatal("waitForFileEvent should return a command")
+	}
+	if _, ok := cmd().(fileEventMsg); !ok {
+		t.Error("Expected a fileEventMsg once the poll interval passed")
 	}
 }
 
@@ -858,13 +869,13 @@ func TestCheckForNewLines(t *testing.T) {
 	tmpFile.Close()
 
 	// Test check for new lines (it's a standalone function, not a method)
-	cmd := checkForNewLines(tmpFile.Name(), 0, 1)
+	cmd := checkForNewLines(tmpFile.Name(), followState{}, 1)
 	if cmd == nil {
 		t.Error("checkForNewLines should return a command")
 	}
 
 	// Test with nonexistent file
-	cmd = checkForNewLines("nonexistent.log", 0, 1)
+	cmd = checkForNewLines("nonexistent.log", followState{}, 1)
 	if cmd == nil {
 		t.Error("checkForNewLines should return a command even for missing files")
 	}
@@ -902,19 +913,19 @@ func TestLoadToEndCmd(t *testing.T) {
 	tmpFile.Close()
 
 	// Open file for testing
-	file, err := os.Open(tmpFile.Name())
+	file, err := openLogFile(tmpFile.Name())
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer file.Close()
 
-	cmd := loadToEndCmd(tmpFile.Name(), file, 1)
+	cmd := loadToEndCmd(tmpFile.Name(), file, nil, 1)
 	if cmd == nil {
 		t.Error("loadToEndCmd should return a command")
 	}
 
 	// Test with nonexistent file
-	cmd = loadToEndCmd("nonexistent.log", nil, 1)
+	cmd = loadToEndCmd("nonexistent.log", nil, nil, 1)
 	if cmd == nil {
 		t.Error("loadToEndCmd should return a command even for missing files")
 	}
@@ -1392,11 +1403,11 @@ func TestMessageTypes(t *testing.T) {
 	_ = newModel.(Model)
 	// Should handle new lines message
 
-	// Test tickMsg
-	tick := tickMsg{}
-	newModel, _ = model.Update(tick)
+	// Test fileEventMsg
+	event := fileEventMsg{}
+	newModel, _ = model.Update(event)
 	_ = newModel.(Model)
-	// Should handle tick message
+	// Should handle file event message
 
 	// Test loadMoreLinesMsg
 	loadMore := loadMoreLinesMsg{err: nil}
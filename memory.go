@@ -0,0 +1,221 @@
+package main
+
+import (
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Estimates used to turn lines into bytes, since measuring the real size of
+// decoded JSON would cost more than decoding it
+const (
+	lineOverhead      = 128 // Bytes per line beyond its raw text
+	decodedCostFactor = 4   // Decoded JSON size relative to the raw line
+)
+
+// memoryBudget limits how much of the log is kept in memory, 0 meaning unlimited
+type memoryBudget struct {
+	maxLines int
+	maxBytes int64
+}
+
+// enabled reports whether any limit is set
+func (b memoryBudget) enabled() bool {
+	return b.maxLines > 0 || b.maxBytes > 0
+}
+
+// data returns the decoded JSON of a line, parsing the raw line again when
+// it was evicted to save memory
+func (line LogLine) data() interface{} {
+	if line.JSONData == nil && line.IsValid {
+		jsonData, _ := decodeJSONLine(line.RawLine)
+		return jsonData
+	}
+	return line.JSONData
+}
+
+// lineCost estimates the memory held by a line
+func lineCost(line LogLine) int64 {
+	cost := int64(len(line.RawLine)) + lineOverhead
+	if line.JSONData != nil {
+		cost += int64(len(line.RawLine)) * decodedCostFactor
+	}
+	return cost
+}
+
+// focusLineIndex returns the index in m.lines of the line the user is
+// looking at, or is about to be taken to while reading up to a line
+func (m Model) focusLineIndex() int {
+	lineNumber := 0
+	if m.showSpinner {
+		lineNumber = m.pendingGoto // The end of the file when 0
+	} else if visibleLines := m.getVisibleLines(); m.cursor >= 0 && m.cursor < len(visibleLines) {
+		lineNumber = visibleLines[m.cursor].LineNumber
+	}
+	if lineNumber <= 0 {
+		return len(m.lines) - 1
+	}
+
+	i := sort.Search(len(m.lines), func(i int) bool {
+		return m.lines[i].LineNumber >= lineNumber
+	})
+	if i == len(m.lines) {
+		i--
+	}
+	return i
+}
+
+// enforceMemoryBudget evicts decoded JSON and then drops lines until what's
+// in memory fits the budget, keeping a screen's worth of lines around the
+// cursor whatever the budget
+func (m *Model) enforceMemoryBudget() {
+	if !m.budget.enabled() || len(m.lines) == 0 {
+		return
+	}
+	if m.linesShared() {
+		return // Jobs read the lines in place, enforced once filtering finishes
+	}
+
+	var total int64
+	for _, line := range m.lines {
+		total += lineCost(line)
+	}
+
+	center := m.focusLineIndex()
+	margin := m.height
+	overBytes := func() bool { return m.budget.maxBytes > 0 && total > m.budget.maxBytes }
+
+	// Evict decoded JSON, farthest from the cursor first
+	if overBytes() {
+		for top, bottom := 0, len(m.lines)-1; overBytes() && (center-top > margin || bottom-center > margin); {
+			i := bottom
+			if center-top >= bottom-center {
+				i = top
+				top++
+			} else {
+				bottom--
+			}
+			if m.lines[i].JSONData != nil {
+				total -= int64(len(m.lines[i].RawLine)) * decodedCostFactor
+				m.lines[i].JSONData = nil
+			}
+		}
+		m.syncFilteredLines()
+	}
+
+	// Then drop whole lines. Lines below the cursor are only dropped when
+	// they can be read again through the index, and never while other
+	// lines are being loaded in that direction.
+	canDropTop := !m.loadingPrevLines
+	canDropBottom := m.canJump() && !m.loadingMoreLines && !m.showSpinner
+	first, end := 0, len(m.lines)
+	for (m.budget.maxLines > 0 && end-first > m.budget.maxLines) || overBytes() {
+		switch {
+		case canDropTop && center-first > margin && center-first >= end-1-center:
+			total -= lineCost(m.lines[first])
+			first++
+		case canDropBottom && end-1-center > margin:
+			end--
+			total -= lineCost(m.lines[end])
+		case canDropTop && center-first > margin:
+			total -= lineCost(m.lines[first])
+			first++
+		default:
+			m.residentBytes = total
+			m.dropLines(first, end)
+			return // Nothing left that may be dropped
+		}
+	}
+	m.residentBytes = total
+	m.dropLines(first, end)
+}
+
+// syncFilteredLines copies evicted JSON from m.lines to the matching filtered lines
+func (m *Model) syncFilteredLines() {
+	if len(m.filters) == 0 {
+		return
+	}
+	i := 0
+	for j := range m.filteredLines {
+		for i < len(m.lines) && m.lines[i].LineNumber < m.filteredLines[j].LineNumber {
+			i++
+		}
+		if i < len(m.lines) && m.lines[i].LineNumber == m.filteredLines[j].LineNumber {
+			m.filteredLines[j].JSONData = m.lines[i].JSONData
+		}
+	}
+	m.contextCache.reset()
+}
+
+// dropLines keeps only m.lines[first:end], keeping the cursor on the same line
+func (m *Model) dropLines(first, end int) {
+	if first == 0 && end == len(m.lines) {
+		return
+	}
+
+	visibleBefore := m.getVisibleLines()
+	cursorLineNumber := 0
+	if m.cursor >= 0 && m.cursor < len(visibleBefore) {
+		cursorLineNumber = visibleBefore[m.cursor].LineNumber
+	}
+	viewportOffset := m.cursor - m.viewport
+	lineScrollOffset := m.lineScrollOffset
+	droppedBelow := end < len(m.lines)
+
+	// Copy so the dropped lines can be garbage collected
+	m.lines = append([]LogLine(nil), m.lines[first:end]...)
+
+	if droppedBelow {
+		// The lines below are read again by lazy loading, which also
+		// picks up anything appended to the file in the meantime
+		m.lastLineNum = m.lines[len(m.lines)-1].LineNumber
+		m.isFileFullyLoaded = false
+		if m.file != nil {
+			m.file.Close()
+			m.file = nil
+		}
+	}
+
+	if len(m.filters) > 0 {
+		lo, hi := m.lines[0].LineNumber, m.lastLineNum
+		var kept []LogLine
+		for _, line := range m.filteredLines {
+			if line.LineNumber >= lo && line.LineNumber <= hi {
+				kept = append(kept, line)
+			}
+		}
+		m.filteredLines = kept
+	}
+
+	if cursorLineNumber > 0 {
+		m.restorePositionAfterFilter(cursorLineNumber)
+		m.viewport = m.cursor - viewportOffset
+		if m.viewport < 0 {
+			m.viewport = 0
+		}
+		m.lineScrollOffset = lineScrollOffset
+	}
+}
+
+// reloadLinesCmd reads the next chunk of lines after some were dropped,
+// reopening the file through the index
+func reloadLinesCmd(filename string, index *lineIndex, nextLineNumber int) tea.Cmd {
+	return func() tea.Msg {
+		file, err := reopenLogFile(filename, index, nextLineNumber)
+		if err != nil {
+			return loadMoreLinesMsg{err: err, isComplete: true}
+		}
+
+		newLines, atEOF, err := file.readLines(nextLineNumber, lazyLoadChunkSize)
+		return loadMoreLinesMsg{newLines: newLines, file: file, err: err, isComplete: atEOF}
+	}
+}
+
+// nextChunkCmd returns the command reading the lines after the last one in
+// memory, reopening the file when lines below were dropped
+func (m Model) nextChunkCmd() tea.Cmd {
+	if m.file == nil && m.canJump() {
+		return reloadLinesCmd(m.filename, m.index, m.lastLineNum+1)
+	}
+	return loadMoreLinesCmd(m.file, m.lastLineNum+1)
+}
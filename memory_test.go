@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestEvictedLineData tests that evicted JSON is parsed again when needed
+func TestEvictedLineData(t *testing.T) {
+	line := parseLogLine(1, `{"level": "error", "n": 1}`)
+	line.JSONData = nil
+
+	data, _ := line.data().(map[string]interface{})
+	if data["level"] != "error" {
+		t.Errorf("Expected the line to be parsed again, got %v", data)
+	}
+
+	invalid := parseLogLine(2, "not json")
+	if invalid.data() != nil {
+		t.Error("Invalid lines have no data")
+	}
+}
+
+// TestMemoryBudgetMaxLines tests that tailing drops the oldest lines
+func TestMemoryBudgetMaxLines(t *testing.T) {
+	model := Model{
+		height:            10,
+		width:             120,
+		tailMode:          true,
+		isFileFullyLoaded: true,
+		budget:            memoryBudget{maxLines: 100},
+	}
+
+	for batch := 0; batch < 10; batch++ {
+		var newLines []LogLine
+		for i := 1; i <= 50; i++ {
+			n := batch*50 + i
+			newLines = append(newLines, parseLogLine(n, fmt.Sprintf(`{"n": %d}`, n)))
+		}
+		model.appendNewLines(newLines)
+	}
+
+	if len(model.lines) != 100 {
+		t.Fatalf("Expected 100 lines in memory, got %d", len(model.lines))
+	}
+	if model.lines[0].LineNumber != 401 || model.lastLineNum != 500 {
+		t.Errorf("Expected lines 401 to 500, got %d to %d", model.lines[0].LineNumber, model.lastLineNum)
+	}
+	if got := model.getVisibleLines()[model.cursor].LineNumber; got != 500 {
+		t.Errorf("Expected the cursor to stay on the last line, got %d", got)
+	}
+	if !strings.Contains(model.View(), "Mem 100 lines") {
+		t.Error("Expected the status bar to show what's in memory")
+	}
+}
+
+// TestMemoryBudgetEvictsJSONFirst tests that decoded JSON goes before lines do
+func TestMemoryBudgetEvictsJSONFirst(t *testing.T) {
+	var lines []LogLine
+	var rawBytes int64
+	for i := 1; i <= 200; i++ {
+		line := parseLogLine(i, fmt.Sprintf(`{"level": "info", "n": %d}`, i))
+		rawBytes += int64(len(line.RawLine)) + lineOverhead
+		lines = append(lines, line)
+	}
+
+	filter, err := newFilter(".n > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := Model{
+		lines:             lines,
+		filters:           []Filter{filter},
+		height:            10,
+		width:             80,
+		cursor:            199,
+		lastLineNum:       200,
+		isFileFullyLoaded: true,
+		budget:            memoryBudget{maxBytes: rawBytes * 11 / 10},
+	}
+	model.applyFilters()
+	model.enforceMemoryBudget()
+
+	if len(model.lines) != 200 {
+		t.Fatalf("No lines should be dropped while evicting JSON is enough, got %d", len(model.lines))
+	}
+	if model.lines[0].JSONData != nil || model.filteredLines[0].JSONData != nil {
+		t.Error("Expected JSON far from the cursor to be evicted")
+	}
+	if model.lines[199].JSONData == nil {
+		t.Error("Lines near the cursor should keep their JSON")
+	}
+	if model.residentBytes > model.budget.maxBytes {
+		t.Errorf("Expected at most %d bytes resident, got %d", model.budget.maxBytes, model.residentBytes)
+	}
+
+	// Filters still work on lines whose JSON was evicted
+	model.applyFilters()
+	if len(model.filteredLines) != 200 {
+		t.Errorf("Expected every line to pass the filter, got %d", len(model.filteredLines))
+	}
+}
+
+// TestMemoryBudgetReloadsDroppedLines tests that lines dropped below the
+// cursor are read again through the index
+func TestMemoryBudgetReloadsDroppedLines(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+	model.budget = memoryBudget{maxLines: 1500}
+	model = runCmd(model, model.goToLine(4500))
+
+	// Scroll up through two blocks
+	for i := 0; i < 2; i++ {
+		model.cursor = 0
+		model.viewport = 0
+		cmd := model.loadPrevLinesIfNeeded()
+		if cmd == nil {
+			t.Fatal("Expected the lines above to be loaded")
+		}
+		newModel, _ := model.Update(cmd())
+		model = newModel.(Model)
+	}
+
+	if len(model.lines) > 1500 {
+		t.Fatalf("Expected at most 1500 lines in memory, got %d", len(model.lines))
+	}
+	if model.isFileFullyLoaded || model.file != nil {
+		t.Fatal("Expected the end of the file to be dropped")
+	}
+
+	// Scroll back down to the end of what's in memory
+	model.cursor = len(model.getVisibleLines()) - 1
+	lastLineNum := model.lastLineNum
+	newModel, _ := model.Update(model.nextChunkCmd()())
+	model = newModel.(Model)
+
+	if model.lastLineNum != lastLineNum+lazyLoadChunkSize {
+		t.Errorf("Expected %d more lines, last line is now %d", lazyLoadChunkSize, model.lastLineNum)
+	}
+	for i := 1; i < len(model.lines); i++ {
+		if model.lines[i].LineNumber != model.lines[i-1].LineNumber+1 {
+			t.Fatalf("Line %d follows line %d", model.lines[i].LineNumber, model.lines[i-1].LineNumber)
+		}
+	}
+	last := model.lines[len(model.lines)-1]
+	if !strings.Contains(last.RawLine, fmt.Sprintf(`"line": %d,`, last.LineNumber)) {
+		t.Errorf("Reloaded line %d has the wrong content: %q", last.LineNumber, last.RawLine)
+	}
+}
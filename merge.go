@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/itchyny/gojq"
+)
+
+// defaultTimeField is the JQ path used to order lines from multiple files
+const defaultTimeField = ".timestamp"
+
+// Colors used for the source column, assigned to files in order
+var sourceColors = []lipgloss.Color{
+	lipgloss.Color("#00AFAF"),
+	lipgloss.Color("#D787FF"),
+	lipgloss.Color("#87D700"),
+	lipgloss.Color("#FFAF00"),
+	lipgloss.Color("#FF5F87"),
+	lipgloss.Color("#5FAFFF"),
+}
+
+// Layouts tried when a timestamp field is a string
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// logSource is one of the files interleaved into a merged view
+type logSource struct {
+	filename string      // Path used to read the file
+	label    string      // Name shown in the source column
+	follow   followState // Track file size and identity for change detection
+}
+
+// newLogSources creates the sources for a merged view, labelled by base name
+func newLogSources(filenames []string) []logSource {
+	counts := make(map[string]int)
+	for _, filename := range filenames {
+		counts[filepath.Base(filename)]++
+	}
+
+	sources := make([]logSource, len(filenames))
+	for i, filename := range filenames {
+		// Fall back to the full path when base names collide
+		label := filepath.Base(filename)
+		if counts[label] > 1 {
+			label = filename
+		}
+		sources[i] = logSource{
+			filename: filename,
+			label:    label,
+		}
+	}
+	return sources
+}
+
+// compileTimeField compiles the JQ expression that extracts a line's timestamp
+func compileTimeField(expression string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return gojq.Compile(query)
+}
+
+// lineTimestamp extracts the timestamp of a line as Unix nanoseconds
+func lineTimestamp(line LogLine, timeQuery *gojq.Code) (int64, bool) {
+	if !line.IsValid || timeQuery == nil {
+		return 0, false
+	}
+
+	iter := timeQuery.Run(line.data())
+	result, ok := iter.Next()
+	if !ok {
+		return 0, false
+	}
+	if _, isErr := result.(error); isErr {
+		return 0, false
+	}
+
+	return parseTimestamp(result)
+}
+
+// parseTimestamp converts a JSON value into Unix nanoseconds. Strings are
+// parsed with common layouts and numbers are treated as epoch seconds,
+// milliseconds, microseconds or nanoseconds depending on their magnitude.
+func parseTimestamp(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UnixNano(), true
+			}
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return epochToNanos(f), true
+		}
+	case float64:
+		return epochToNanos(v), true
+	case int:
+		if v >= 1e17 || v <= -1e17 {
+			return int64(v), true // Nanoseconds, kept exact
+		}
+		return epochToNanos(float64(v)), true
+	}
+	return 0, false
+}
+
+// epochToNanos guesses the unit of a numeric epoch timestamp
+func epochToNanos(v float64) int64 {
+	abs := math.Abs(v)
+	switch {
+	case abs < 1e11:
+		return int64(v * 1e9)
+	case abs < 1e14:
+		return int64(v * 1e6)
+	case abs < 1e17:
+		return int64(v * 1e3)
+	}
+	return int64(v)
+}
+
+// mergeByTimestamp interleaves the lines of several files by timestamp and
+// renumbers them in merged order. Each file is assumed to already be in
+// order, and lines without a timestamp (stack traces, banners) stay directly
+// after the line that precedes them in their own file.
+func mergeByTimestamp(groups [][]LogLine, timeQuery *gojq.Code, firstLineNumber int) []LogLine {
+	total := 0
+	keys := make([][]int64, len(groups))
+	for i, group := range groups {
+		total += len(group)
+		keys[i] = make([]int64, len(group))
+		var last int64 = math.MinInt64
+		for j, line := range group {
+			if ts, ok := lineTimestamp(line, timeQuery); ok {
+				last = ts
+			}
+			keys[i][j] = last
+		}
+	}
+
+	merged := make([]LogLine, 0, total)
+	heads := make([]int, len(groups))
+	for len(merged) < total {
+		// Pick the earliest head, preferring earlier files on ties
+		best := -1
+		for i := range groups {
+			if heads[i] >= len(groups[i]) {
+				continue
+			}
+			if best == -1 || keys[i][heads[i]] < keys[best][heads[best]] {
+				best = i
+			}
+		}
+
+		line := groups[best][heads[best]]
+		line.LineNumber = firstLineNumber + len(merged)
+		merged = append(merged, line)
+		heads[best]++
+	}
+
+	return merged
+}
+
+// loadMergedLines loads every source in full and interleaves them by
+// timestamp, following each source from where reading it stopped
+func loadMergedLines(sources []logSource, timeQuery *gojq.Code) ([]LogLine, error) {
+	groups := make([][]LogLine, len(sources))
+	for i, source := range sources {
+		file, err := openLogFile(source.filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.filename, err)
+		}
+		lines, _, err := file.readLines(1, 0)
+		sources[i].follow.continueFrom(file) // Follow from where reading stopped
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.filename, err)
+		}
+		for j := range lines {
+			lines[j].Source = source.label
+		}
+		groups[i] = lines
+	}
+
+	return mergeByTimestamp(groups, timeQuery, 1), nil
+}
+
+// checkSourcesForNewLines checks every source of a merged view for new lines
+// and interleaves whatever was appended since the last check. The new lines
+// are only ordered among themselves and go after every line already shown,
+// even one with a later timestamp from another source, since lines shown
+// aren't moved. Like checkForNewLines, a message is returned even when
+// nothing changed.
+func checkSourcesForNewLines(sources []logSource, timeQuery *gojq.Code, lastLineNum int) tea.Cmd {
+	// Copy the states so the command doesn't race with the model updating them
+	sources = append([]logSource(nil), sources...)
+
+	return func() tea.Msg {
+		groups := make([][]LogLine, 0, len(sources))
+		states := make([]followState, len(sources))
+		var finished []LogLine
+		for i, source := range sources {
+			lines, state := readNewLines(source.filename, source.follow, 1)
+			for j := range lines {
+				lines[j].Source = source.label
+			}
+			done, lines := splitFinished(lines, state)
+			finished = append(finished, done...)
+			if len(lines) > 0 {
+				groups = append(groups, lines)
+			}
+			states[i] = state
+		}
+
+		return followMsg{
+			newLines:        mergeByTimestamp(groups, timeQuery, lastLineNum+1),
+			finished:        finished,
+			states:          states,
+			firstLineNumber: lastLineNum + 1,
+		}
+	}
+}
+
+// sourceColumnWidth returns the width of the source column including its
+// trailing space, or 0 when only one file is shown
+func (m Model) sourceColumnWidth() int {
+	width := 0
+	for _, source := range m.sources {
+		width = max(width, lipgloss.Width(source.label))
+	}
+	if width == 0 {
+		return 0
+	}
+	return width + 1
+}
+
+// renderSourceLabel renders the padded source column for a line, colored by
+// file unless the line is selected
+func (m Model) renderSourceLabel(line LogLine, selected bool) string {
+	width := m.sourceColumnWidth()
+	if width == 0 {
+		return ""
+	}
+
+	label := line.Source + strings.Repeat(" ", max(width-lipgloss.Width(line.Source), 0))
+	if selected {
+		return label
+	}
+
+	for i, source := range m.sources {
+		if source.label == line.Source {
+			color := sourceColors[i%len(sourceColors)]
+			return lipgloss.NewStyle().Foreground(color).Render(label)
+		}
+	}
+	return label
+}
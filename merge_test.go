@@ -0,0 +1,230 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TestParseTimestamp tests timestamp extraction from JSON values
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC).UnixNano()
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   int64
+		wantOK bool
+	}{
+		{"RFC3339", "2023-01-01T10:00:00Z", want, true},
+		{"RFC3339 with offset", "2023-01-01T11:00:00+01:00", want, true},
+		{"space separated", "2023-01-01 10:00:00", want, true},
+		{"epoch seconds", float64(1672567200), want, true},
+		{"epoch milliseconds", float64(1672567200000), want, true},
+		{"epoch nanoseconds", float64(1672567200000000000), want, true},
+		{"numeric string", "1672567200", want, true},
+		{"garbage string", "yesterday", 0, false},
+		{"null", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTimestamp(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("parseTimestamp(%v) ok = %v, expected %v", tt.value, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseTimestamp(%v) = %d, expected %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergeByTimestamp tests interleaving lines from several files
+func TestMergeByTimestamp(t *testing.T) {
+	timeQuery, err := compileTimeField(defaultTimeField)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := []LogLine{
+		parseLogLine(1, `{"timestamp": "2023-01-01T10:00:00Z", "msg": "a1"}`),
+		parseLogLine(2, `{"timestamp": "2023-01-01T10:00:03Z", "msg": "a2"}`),
+		parseLogLine(3, `panic: trace for a2`),
+	}
+	worker := []LogLine{
+		parseLogLine(1, `{"timestamp": "2023-01-01T10:00:01Z", "msg": "w1"}`),
+		parseLogLine(2, `{"timestamp": "2023-01-01T10:00:04Z", "msg": "w2"}`),
+	}
+
+	merged := mergeByTimestamp([][]LogLine{api, worker}, timeQuery, 1)
+
+	expected := []string{"a1", "w1", "a2", "panic: trace for a2", "w2"}
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %d merged lines, got %d", len(expected), len(merged))
+	}
+	for i, line := range merged {
+		if !strings.Contains(line.RawLine, expected[i]) {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], line.RawLine)
+		}
+		if line.LineNumber != i+1 {
+			t.Errorf("Line %d: expected line number %d, got %d", i, i+1, line.LineNumber)
+		}
+	}
+}
+
+// TestNewLogSources tests source labels for merged files
+func TestNewLogSources(t *testing.T) {
+	sources := newLogSources([]string{"/var/log/api.log", "/var/log/worker.log"})
+	if sources[0].label != "api.log" || sources[1].label != "worker.log" {
+		t.Errorf("Expected base name labels, got %q and %q", sources[0].label, sources[1].label)
+	}
+
+	sources = newLogSources([]string{"a/app.log", "b/app.log"})
+	if sources[0].label == sources[1].label {
+		t.Errorf("Colliding base names should get distinct labels, got %q", sources[0].label)
+	}
+}
+
+// TestLoadMergedLines tests loading and merging files from disk
+func TestLoadMergedLines(t *testing.T) {
+	dir := t.TempDir()
+	apiFile := filepath.Join(dir, "api.log")
+	dbFile := filepath.Join(dir, "db.log")
+
+	apiContent := `{"timestamp": 2, "msg": "api"}` + "\n"
+	dbContent := `{"timestamp": 1, "msg": "db"}` + "\n" + `{"timestamp": 3, "msg": "db"}` + "\n"
+	if err := os.WriteFile(apiFile, []byte(apiContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dbFile, []byte(dbContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	timeQuery, err := compileTimeField(defaultTimeField)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := loadMergedLines(newLogSources([]string{apiFile, dbFile}), timeQuery)
+	if err != nil {
+		t.Fatalf("loadMergedLines failed: %v", err)
+	}
+
+	expectedSources := []string{"db.log", "api.log", "db.log"}
+	if len(lines) != len(expectedSources) {
+		t.Fatalf("Expected %d lines, got %d", len(expectedSources), len(lines))
+	}
+	for i, line := range lines {
+		if line.Source != expectedSources[i] {
+			t.Errorf("Line %d: expected source %q, got %q", i, expectedSources[i], line.Source)
+		}
+	}
+
+	// Missing files are reported
+	if _, err := loadMergedLines(newLogSources([]string{apiFile, "nonexistent.log"}), timeQuery); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// TestSourceFilterVariable tests filtering on $source
+func TestSourceFilterVariable(t *testing.T) {
+	model := Model{
+		lines: []LogLine{
+			{LineNumber: 1, RawLine: `{}`, IsValid: true, JSONData: map[string]interface{}{}, Source: "api.log"},
+			{LineNumber: 2, RawLine: `{}`, IsValid: true, JSONData: map[string]interface{}{}, Source: "db.log"},
+		},
+	}
+
+	if err := model.addFilter(`$source == "db.log"`); err != nil {
+		t.Fatalf("Failed to add $source filter: %v", err)
+	}
+	model.applyFilters()
+
+	if len(model.filteredLines) != 1 || model.filteredLines[0].Source != "db.log" {
+		t.Errorf("Expected only the db.log line, got %v", model.filteredLines)
+	}
+}
+
+// TestCheckSourcesForNewLines tests tailing every file of a merged view
+func TestCheckSourcesForNewLines(t *testing.T) {
+	dir := t.TempDir()
+	apiFile := filepath.Join(dir, "api.log")
+	dbFile := filepath.Join(dir, "db.log")
+	if err := os.WriteFile(apiFile, []byte(`{"timestamp": 1}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dbFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := newLogSources([]string{apiFile, dbFile})
+	sources[0].follow.size = int64(len(`{"timestamp": 1}` + "\n"))
+
+	// Nothing new yet
+	if msg, ok := checkSourcesForNewLines(sources, nil, 1)().(followMsg); !ok || len(msg.newLines) != 0 {
+		t.Errorf("Expected a message without new lines, got %v", msg.newLines)
+	}
+
+	if err := os.WriteFile(dbFile, []byte(`{"timestamp": 2}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := checkSourcesForNewLines(sources, nil, 1)().(followMsg)
+	if !ok || len(msg.newLines) != 1 {
+		t.Fatalf("Expected one new line, got %v", msg.newLines)
+	}
+	if msg.newLines[0].Source != "db.log" || msg.newLines[0].LineNumber != 2 {
+		t.Errorf("Expected db.log line 2, got %s line %d", msg.newLines[0].Source, msg.newLines[0].LineNumber)
+	}
+	if len(msg.states) != 2 || msg.states[1].size != int64(len(`{"timestamp": 2}`+"\n")) {
+		t.Errorf("Expected db.log to be read to its end, got states %+v", msg.states)
+	}
+}
+
+// TestMergedViewRendering tests the source column in the main view
+func TestMergedViewRendering(t *testing.T) {
+	model := Model{
+		lines: []LogLine{
+			{LineNumber: 1, RawLine: `{"msg": "hello"}`, IsValid: true, Source: "api.log"},
+			{LineNumber: 2, RawLine: `not json`, Source: "worker.log"},
+		},
+		sources:           newLogSources([]string{"api.log", "worker.log"}),
+		isFileFullyLoaded: true,
+		height:            5,
+		width:             80,
+	}
+
+	if width := model.sourceColumnWidth(); width != len("worker.log")+1 {
+		t.Errorf("Expected source column width %d, got %d", len("worker.log")+1, width)
+	}
+
+	view := model.View()
+	if !strings.Contains(view, "api.log") || !strings.Contains(view, "worker.log") {
+		t.Error("View should show the source of each line")
+	}
+	if !strings.Contains(view, "[INVALID JSON]") {
+		t.Error("View should still mark invalid lines")
+	}
+
+	// Labels are padded by how wide they show, not their bytes
+	model.sources = newLogSources([]string{"api.log", "журнал.log"})
+	if width := model.sourceColumnWidth(); width != 11 {
+		t.Errorf("Expected source column width 11, got %d", width)
+	}
+	for _, source := range []string{"api.log", "журнал.log"} {
+		if label := model.renderSourceLabel(LogLine{Source: source}, true); lipgloss.Width(label) != 11 {
+			t.Errorf("Expected %s padded to 11 columns, got %q", source, label)
+		}
+	}
+
+	// Single-file views have no source column
+	model.sources = nil
+	if width := model.sourceColumnWidth(); width != 0 {
+		t.Errorf("Expected no source column for a single file, got %d", width)
+	}
+}
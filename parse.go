@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// parseBatchSize is how many lines a worker parses at a time
+const parseBatchSize = 256
+
+// parseInFlightPerWorker bounds how many batches may be read ahead of the
+// one the collector is waiting for, per worker
+const parseInFlightPerWorker = 4
+
+// rawLine is a line as read from the file, before it's parsed
+type rawLine struct {
+	text      string
+	truncated bool
+	offset    int64
+}
+
+// parseBatch is a run of consecutive lines passed through the pipeline
+type parseBatch struct {
+	seq   int
+	first int // Number of the first line
+	raw   []rawLine
+	lines []LogLine
+}
+
+// parse builds the LogLines of a batch
+func (b *parseBatch) parse() {
+	b.lines = make([]LogLine, len(b.raw))
+	for i, raw := range b.raw {
+		line := parseLogLine(b.first+i, raw.text)
+		line.Truncated = raw.truncated
+		line.Offset = raw.offset
+		b.lines[i] = line
+	}
+	b.raw = nil
+}
+
+// parseWorkers returns how many workers parse in parallel
+func parseWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// runParsePipeline parses the batches returned by read, numbering lines from
+// firstLineNumber, and passes them to emit in order. read is called on its
+// own goroutine until it returns no lines.
+func runParsePipeline(read func() []rawLine, firstLineNumber int, emit func([]LogLine)) {
+	workers := parseWorkers()
+	jobs := make(chan *parseBatch, workers)
+	results := make(chan *parseBatch, workers)
+	tokens := make(chan struct{}, workers*parseInFlightPerWorker)
+
+	// Reader
+	go func() {
+		defer close(jobs)
+		first := firstLineNumber
+		for seq := 0; ; seq++ {
+			tokens <- struct{}{} // Wait for the collector to catch up
+			raw := read()
+			if len(raw) == 0 {
+				return
+			}
+			jobs <- &parseBatch{seq: seq, first: first, raw: raw}
+			first += len(raw)
+		}
+	}()
+
+	// Parsers
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				batch.parse()
+				results <- batch
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collector, emitting batches in the order they were read
+	pending := make(map[int]*parseBatch)
+	next := 0
+	for batch := range results {
+		pending[batch.seq] = batch
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			emit(ready.lines)
+			next++
+			<-tokens
+		}
+	}
+}
+
+// parseRawLines parses lines that have already been read, in parallel when
+// there are enough of them
+func parseRawLines(raw []rawLine, firstLineNumber int) []LogLine {
+	if len(raw) <= parseBatchSize {
+		batch := parseBatch{first: firstLineNumber, raw: raw}
+		batch.parse()
+		return batch.lines
+	}
+
+	lines := make([]LogLine, 0, len(raw))
+	rest := raw
+	runParsePipeline(func() []rawLine {
+		n := parseBatchSize
+		if n > len(rest) {
+			n = len(rest)
+		}
+		batch := rest[:n]
+		rest = rest[n:]
+		return batch
+	}, firstLineNumber, func(parsed []LogLine) {
+		lines = append(lines, parsed...)
+	})
+	return lines
+}
+
+// readRawLines reads up to n lines, stopping early at EOF or on an error.
+// With whenBuffered set it also stops before a read that would block, so a
+// slow writer's lines aren't held back waiting for a full batch.
+func (lr *lineReader) readRawLines(n int, whenBuffered bool) ([]rawLine, error) {
+	var raw []rawLine
+	for len(raw) < n {
+		if whenBuffered && len(raw) > 0 && lr.r.Buffered() == 0 {
+			break
+		}
+		offset := lr.offset
+		text, truncated, err := lr.readLine()
+		if err != nil {
+			return raw, err
+		}
+		raw = append(raw, rawLine{text: text, truncated: truncated, offset: offset})
+	}
+	return raw, nil
+}
+
+// readLogLines reads up to maxLines lines, or every remaining line when
+// maxLines is 0, parsing them in parallel. atEOF reports whether the end of
+// the input was reached.
+func (lr *lineReader) readLogLines(firstLineNumber, maxLines int) (lines []LogLine, atEOF bool, err error) {
+	if maxLines > 0 && maxLines <= parseBatchSize {
+		raw, err := lr.readRawLines(maxLines, false)
+		lines = parseRawLines(raw, firstLineNumber)
+		if err == io.EOF {
+			return lines, true, nil
+		}
+		return lines, false, err
+	}
+
+	remaining := maxLines
+	var readErr error
+	runParsePipeline(func() []rawLine {
+		if readErr != nil {
+			return nil
+		}
+		n := parseBatchSize
+		if maxLines > 0 {
+			if remaining == 0 {
+				return nil
+			}
+			if n > remaining {
+				n = remaining
+			}
+		}
+		raw, err := lr.readRawLines(n, false)
+		readErr = err
+		remaining -= len(raw)
+		return raw
+	}, firstLineNumber, func(parsed []LogLine) {
+		lines = append(lines, parsed...)
+	})
+
+	// The reader goroutine has finished once the pipeline returns
+	if readErr == io.EOF {
+		return lines, true, nil
+	}
+	return lines, false, readErr
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// loadAllLinesSequential loads a file the way loadAllLines did before the
+// parse pipeline, parsing one line at a time, to compare against
+func loadAllLinesSequential(filename string) ([]LogLine, error) {
+	file, err := openLogFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []LogLine
+	for lineNumber := 1; ; lineNumber++ {
+		offset := file.reader.offset
+		text, truncated, err := file.reader.readLine()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line := parseLogLine(lineNumber, text)
+		line.Truncated = truncated
+		line.Offset = offset
+		lines = append(lines, line)
+	}
+}
+
+// withParseWorkers runs a test with GOMAXPROCS set so the pipeline has several workers
+func withParseWorkers(t testing.TB, n int) {
+	previous := runtime.GOMAXPROCS(n)
+	t.Cleanup(func() { runtime.GOMAXPROCS(previous) })
+}
+
+// TestReadLogLinesMatchesSequential tests that parallel parsing keeps lines in order
+func TestReadLogLinesMatchesSequential(t *testing.T) {
+	withParseWorkers(t, 8)
+
+	// Mix in invalid lines so batches parse at different speeds
+	var b strings.Builder
+	for i := 1; i <= 10000; i++ {
+		if i%7 == 0 {
+			fmt.Fprintf(&b, "plain text line %d\n", i)
+		} else {
+			fmt.Fprintf(&b, `{"line": %d, "msg": "message number %d"}`+"\n", i, i)
+		}
+	}
+	path := writeTestFile(t, "app.log", []byte(b.String()))
+
+	want, err := loadAllLinesSequential(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadAllLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d lines, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].LineNumber != want[i].LineNumber || got[i].RawLine != want[i].RawLine ||
+			got[i].Offset != want[i].Offset || got[i].IsValid != want[i].IsValid {
+			t.Fatalf("Line %d differs: expected %+v, got %+v", i+1, want[i], got[i])
+		}
+	}
+}
+
+// TestReadLogLinesMaxLines tests that a limited read stops exactly and the next one continues
+func TestReadLogLinesMaxLines(t *testing.T) {
+	withParseWorkers(t, 4)
+	lr := newLineReader(strings.NewReader(generateLogContent(3000)), maxLineSize)
+
+	lines, atEOF, err := lr.readLogLines(1, 1000)
+	if err != nil || atEOF || len(lines) != 1000 {
+		t.Fatalf("Expected 1000 lines, got %d (atEOF %v, err %v)", len(lines), atEOF, err)
+	}
+
+	rest, atEOF, err := lr.readLogLines(1001, 0)
+	if err != nil || !atEOF || len(rest) != 2000 {
+		t.Fatalf("Expected the remaining 2000 lines, got %d (atEOF %v, err %v)", len(rest), atEOF, err)
+	}
+	if rest[0].LineNumber != 1001 || !strings.Contains(rest[0].RawLine, `"line": 1001,`) {
+		t.Errorf("Expected the second read to start at line 1001, got %+v", rest[0])
+	}
+}
+
+// TestParseRawLines tests parsing lines that were already read
+func TestParseRawLines(t *testing.T) {
+	withParseWorkers(t, 4)
+
+	raw := make([]rawLine, 1000)
+	for i := range raw {
+		raw[i] = rawLine{text: fmt.Sprintf(`{"n": %d}`, i), offset: int64(i * 10)}
+	}
+	raw[500].truncated = true
+
+	lines := parseRawLines(raw, 42)
+	if len(lines) != 1000 {
+		t.Fatalf("Expected 1000 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line.LineNumber != 42+i || line.Offset != int64(i*10) || line.RawLine != raw[i].text {
+			t.Fatalf("Line %d out of order: %+v", i, line)
+		}
+	}
+	if !lines[500].Truncated {
+		t.Error("Expected the truncation flag to be kept")
+	}
+}
+
+// benchmarkLoad measures a loader on a generated file
+func benchmarkLoad(b *testing.B, load func(string) ([]LogLine, error)) {
+	content := generateLogContent(100000)
+	path := writeTestFile(b, "bench.log", []byte(content))
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := load(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLoadAllLines measures loading a file through the parse pipeline
+func BenchmarkLoadAllLines(b *testing.B) {
+	benchmarkLoad(b, loadAllLines)
+}
+
+// BenchmarkLoadAllLinesSequential measures loading a file one line at a time, as before
+func BenchmarkLoadAllLinesSequential(b *testing.B) {
+	benchmarkLoad(b, loadAllLinesSequential)
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var collapsedHintStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#808080")).
+	Faint(true)
+
+// identifierPattern matches keys jq paths can use without quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonKind is what kind of value a node holds
+type jsonKind int
+
+const (
+	jsonScalar jsonKind = iota // A string, number, boolean or null
+	jsonObject
+	jsonArray
+)
+
+// jsonNode is a value in the selected line
+type jsonNode struct {
+	kind      jsonKind
+	key       string // Key in the parent object
+	rawKey    string // Key as written, quotes and escapes included
+	index     int    // Position in the parent
+	value     string // As written, objects and arrays included
+	parent    *jsonNode
+	children  []*jsonNode
+	collapsed bool
+}
+
+// prettyTree is the line shown in the pretty view as a tree, with the value
+// the cursor is on
+type prettyTree struct {
+	line   *LogLine
+	root   *jsonNode
+	cursor *jsonNode
+
+	// What's drawn is kept until something is collapsed or expanded, and the
+	// layout until the cursor, the screen width or the search changes too
+	shown     []treeRow
+	styled    []string
+	layout    *prettyLayout
+	layoutFor prettyLayoutKey
+}
+
+// prettyLayoutKey is what a layout of a tree was made for
+type prettyLayoutKey struct {
+	cursor *jsonNode
+	width  int
+	search *regexp.Regexp
+}
+
+// treeRow is a line of the tree, before it's wrapped to the screen
+type treeRow struct {
+	node    *jsonNode
+	closing bool   // Whether it's the closing bracket of an expanded object or array
+	text    string // Indented, but not highlighted
+	hint    string // How much a collapsed object or array holds
+}
+
+// prettyLayout is the pretty view as it's drawn, before it's scrolled
+type prettyLayout struct {
+	lines      []string // Screen lines, wrapped and styled
+	matchLines []int    // Screen lines with search matches
+	rows       []treeRow
+	rowStarts  []int // The screen line each row starts on
+}
+
+// parseJSONTree reads a raw JSON line into a tree, keeping its keys and
+// values as written
+func parseJSONTree(rawLine string) (*jsonNode, error) {
+	decoder := json.NewDecoder(strings.NewReader(rawLine))
+	decoder.UseNumber()
+	root := &jsonNode{}
+	if err := root.read(decoder, rawLine); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// read reads the next value from decoder into the node
+func (n *jsonNode) read(decoder *json.Decoder, rawLine string) error {
+	start := decoder.InputOffset()
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		n.kind = jsonArray
+		if token == json.Delim('{') {
+			n.kind = jsonObject
+		}
+		for decoder.More() {
+			child := &jsonNode{index: len(n.children), parent: n}
+			if n.kind == jsonObject {
+				keyStart := decoder.InputOffset()
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				child.key = key.(string)
+				child.rawKey = rawToken(rawLine, keyStart, decoder.InputOffset())
+			}
+			if err := child.read(decoder, rawLine); err != nil {
+				return err
+			}
+			n.children = append(n.children, child)
+		}
+		if _, err := decoder.Token(); err != nil { // The closing bracket
+			return err
+		}
+	}
+	n.value = rawToken(rawLine, start, decoder.InputOffset())
+	return nil
+}
+
+// rawToken returns a token as written, without the separators before it
+func rawToken(rawLine string, start, end int64) string {
+	return strings.TrimLeft(rawLine[start:end], " \t\r\n,:")
+}
+
+// expandable reports whether the node is an object or array with something in it
+func (n *jsonNode) expandable() bool {
+	return n.kind != jsonScalar && len(n.children) > 0
+}
+
+// expanded reports whether the node's children are shown
+func (n *jsonNode) expanded() bool {
+	return n.expandable() && !n.collapsed
+}
+
+// brackets returns the brackets around the node's children
+func (n *jsonNode) brackets() (string, string) {
+	if n.kind == jsonArray {
+		return "[", "]"
+	}
+	return "{", "}"
+}
+
+// comma returns the comma after the node, when it isn't its parent's last child
+func (n *jsonNode) comma() string {
+	if n.parent == nil || n.index == len(n.parent.children)-1 {
+		return ""
+	}
+	return ","
+}
+
+// text returns the node's line without its indentation, collapsed or not
+func (n *jsonNode) text(collapsed bool) string {
+	var b strings.Builder
+	if n.parent != nil && n.parent.kind == jsonObject {
+		b.WriteString(n.rawKey + ": ")
+	}
+	open, close := n.brackets()
+	switch {
+	case n.kind == jsonScalar:
+		b.WriteString(n.value + n.comma())
+	case len(n.children) == 0:
+		b.WriteString(open + close + n.comma())
+	case collapsed:
+		b.WriteString(open + "…" + close + n.comma())
+	default:
+		b.WriteString(open)
+	}
+	return b.String()
+}
+
+// hint returns how much a collapsed node holds
+func (n *jsonNode) hint() string {
+	noun := "key"
+	if n.kind == jsonArray {
+		noun = "item"
+	}
+	if len(n.children) != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("%d %s", len(n.children), noun)
+}
+
+// path returns the jq path to the node, e.g. .request.headers["x-id"]
+func (n *jsonNode) path() string {
+	if n.parent == nil {
+		return "."
+	}
+	parent := ""
+	if n.parent.parent != nil {
+		parent = n.parent.path()
+	}
+	if n.parent.kind == jsonArray {
+		return indexPath(parent, strconv.Itoa(n.index))
+	}
+	return keyPath(parent, n.key)
+}
+
+// keyPath returns the jq path to a key of the value at path, "" being the
+// whole line
+func keyPath(path, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	if path == "" {
+		path = "."
+	}
+	return path + "[" + string(quoted) + "]"
+}
+
+// indexPath returns the jq path to an item of the array at path, or to all
+// of them when index is empty
+func indexPath(path, index string) string {
+	if path == "" {
+		path = "."
+	}
+	return path + "[" + index + "]"
+}
+
+// newPrettyTree returns the tree of a line, expanded, or nil when it isn't JSON
+func newPrettyTree(line *LogLine) *prettyTree {
+	if line == nil || !line.IsValid {
+		return nil
+	}
+	root, err := parseJSONTree(line.RawLine)
+	if err != nil {
+		return nil
+	}
+	return &prettyTree{line: line, root: root, cursor: root}
+}
+
+// rows returns the lines of the tree, without what's collapsed
+func (t *prettyTree) rows() []treeRow {
+	if t.shown != nil {
+		return t.shown
+	}
+	var rows []treeRow
+	var walk func(n *jsonNode, indent string)
+	walk = func(n *jsonNode, indent string) {
+		row := treeRow{node: n, text: indent + n.text(n.collapsed)}
+		if n.expandable() && n.collapsed {
+			row.hint = n.hint()
+		}
+		rows = append(rows, row)
+		if !n.expanded() {
+			return
+		}
+		for _, child := range n.children {
+			walk(child, indent+"  ")
+		}
+		_, close := n.brackets()
+		rows = append(rows, treeRow{node: n, closing: true, text: indent + close + n.comma()})
+	}
+	walk(t.root, "")
+	t.shown = rows
+	return rows
+}
+
+// styledRows returns the text of each row with its syntax highlighted, or as
+// it is when highlighting fails
+func (t *prettyTree) styledRows() []string {
+	if t.styled != nil {
+		return t.styled
+	}
+	rows := t.rows()
+	texts := make([]string, len(rows))
+	for i, row := range rows {
+		texts[i] = row.text
+	}
+	t.styled = texts
+	if highlighted, err := highlightJSON(strings.Join(texts, "\n")); err == nil {
+		if split := strings.Split(highlighted, "\n"); len(split) >= len(texts) {
+			t.styled = split
+		}
+	}
+	return t.styled
+}
+
+// changed forgets what was drawn, after something was collapsed or expanded
+func (t *prettyTree) changed() {
+	t.shown = nil
+	t.styled = nil
+	t.layout = nil
+}
+
+// nodes returns every node in the tree in the order they're written,
+// collapsed or not
+func (t *prettyTree) nodes() []*jsonNode {
+	var nodes []*jsonNode
+	var walk func(n *jsonNode)
+	walk = func(n *jsonNode) {
+		nodes = append(nodes, n)
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(t.root)
+	return nodes
+}
+
+// move moves the cursor up or down the rows shown, skipping closing brackets
+func (t *prettyTree) move(delta int) {
+	var shown []*jsonNode
+	current := 0
+	for _, row := range t.rows() {
+		if row.closing {
+			continue
+		}
+		if row.node == t.cursor {
+			current = len(shown)
+		}
+		shown = append(shown, row.node)
+	}
+	t.cursor = shown[max(0, min(current+delta, len(shown)-1))]
+}
+
+// collapse collapses the node under the cursor, or moves to its parent when
+// there's nothing to collapse
+func (t *prettyTree) collapse() {
+	if t.cursor.expanded() {
+		t.cursor.collapsed = true
+		t.changed()
+	} else if t.cursor.parent != nil {
+		t.cursor = t.cursor.parent
+	}
+}
+
+// expand expands the node under the cursor, or moves to its first child when
+// it's already expanded
+func (t *prettyTree) expand() {
+	if t.cursor.expanded() {
+		t.cursor = t.cursor.children[0]
+	} else if t.cursor.expandable() {
+		t.cursor.collapsed = false
+		t.changed()
+	}
+}
+
+// sibling moves the cursor to the next or previous key or item of the same parent
+func (t *prettyTree) sibling(delta int) {
+	parent := t.cursor.parent
+	if parent == nil {
+		return
+	}
+	if i := t.cursor.index + delta; i >= 0 && i < len(parent.children) {
+		t.cursor = parent.children[i]
+	}
+}
+
+// setCollapsed collapses or expands everything below the root, moving the
+// cursor out of what's collapsed
+func (t *prettyTree) setCollapsed(collapsed bool) {
+	for _, n := range t.nodes() {
+		if n != t.root {
+			n.collapsed = collapsed
+		}
+	}
+	t.changed()
+	for n := t.cursor.parent; n != nil; n = n.parent {
+		if n.collapsed {
+			t.cursor = n
+		}
+	}
+}
+
+// reveal expands everything around a node, so it's shown
+func (t *prettyTree) reveal(node *jsonNode) {
+	for n := node.parent; n != nil; n = n.parent {
+		n.collapsed = false
+	}
+	t.changed()
+}
+
+// tree returns the tree of the line in the pretty view, nil when it isn't JSON
+func (m *Model) tree() *prettyTree {
+	if m.prettyTree == nil || m.prettyTree.line != m.selectedLine {
+		m.prettyTree = newPrettyTree(m.selectedLine)
+	}
+	return m.prettyTree
+}
+
+// closePretty goes back from the pretty view to the lines
+func (m *Model) closePretty() {
+	m.showPretty = false
+	m.selectedLine = nil
+	m.prettyTree = nil
+	m.prettyViewport = 0
+}
+
+// prettyLayout lays out the selected line for the pretty view: as a tree
+// with its syntax highlighted and the cursor's row picked out when it's
+// JSON, or as it is otherwise. Rows with search matches are shown without
+// highlighting, so the matches stand out. A tree's layout is reused until
+// something changes.
+func (m Model) prettyLayout() prettyLayout {
+	var layout prettyLayout
+	tree := m.tree()
+	if tree == nil {
+		if !m.selectedLine.IsValid {
+			layout.lines = append(layout.lines, invalidLineHeader(m.selectedLine))
+		}
+		for _, wrapped := range m.wrapLine(m.selectedLine.RawLine, m.width-2) {
+			if matches := m.searchMatches(wrapped); len(matches) > 0 {
+				layout.matchLines = append(layout.matchLines, len(layout.lines))
+				wrapped = highlightMatches(wrapped, matches, lipgloss.NewStyle())
+			}
+			layout.lines = append(layout.lines, wrapped)
+		}
+		return layout
+	}
+
+	key := prettyLayoutKey{cursor: tree.cursor, width: m.width}
+	if m.activeSearch != nil {
+		key.search = m.activeSearch.re
+	}
+	if tree.layout != nil && tree.layoutFor == key {
+		return *tree.layout
+	}
+
+	layout.rows = tree.rows()
+	styled := tree.styledRows()
+	width := m.width - 4 // Account for the cursor and the reserved rightmost column
+	for i, row := range layout.rows {
+		layout.rowStarts = append(layout.rowStarts, len(layout.lines))
+		selected := row.node == tree.cursor && !row.closing
+		base := lipgloss.NewStyle()
+		gutter := "  "
+		if selected {
+			base = selectedLineStyle.UnsetPadding()
+			gutter = "> "
+		}
+
+		var pieces []string
+		if !selected && m.searchMatches(row.text) == nil {
+			pieces = m.wrapLine(styled[i], width)
+		} else {
+			for _, piece := range m.wrapLine(row.text, width) {
+				matches := m.searchMatches(piece)
+				if len(matches) > 0 {
+					layout.matchLines = append(layout.matchLines, len(layout.lines)+len(pieces))
+				}
+				pieces = append(pieces, highlightMatches(piece, matches, base))
+			}
+		}
+		if row.hint != "" {
+			pieces[len(pieces)-1] += " " + collapsedHintStyle.Render(row.hint)
+		}
+		for j, piece := range pieces {
+			if j > 0 {
+				gutter = "  "
+			}
+			layout.lines = append(layout.lines, base.Render(gutter)+piece)
+		}
+	}
+	tree.layout, tree.layoutFor = &layout, key
+	return layout
+}
+
+// rowLines returns the screen lines the row of a node starts and ends before
+func (l prettyLayout) rowLines(node *jsonNode) (int, int) {
+	for i, row := range l.rows {
+		if row.node != node || row.closing {
+			continue
+		}
+		end := len(l.lines)
+		if i+1 < len(l.rowStarts) {
+			end = l.rowStarts[i+1]
+		}
+		return l.rowStarts[i], end
+	}
+	return 0, 0
+}
+
+// prettyPageSize returns how many lines of the pretty view fit on the screen
+func (m Model) prettyPageSize() int {
+	return max(m.height-1, 1) // Account for status bar
+}
+
+// scrollToPrettyCursor scrolls the pretty view as little as it takes to show
+// the cursor's row
+func (m *Model) scrollToPrettyCursor() {
+	tree := m.tree()
+	if tree == nil {
+		return
+	}
+	layout := m.prettyLayout()
+	pageSize := m.prettyPageSize()
+	start, end := layout.rowLines(tree.cursor)
+	if end > m.prettyViewport+pageSize {
+		m.prettyViewport = end - pageSize
+	}
+	if start < m.prettyViewport {
+		m.prettyViewport = start
+	}
+	m.prettyViewport = min(m.prettyViewport, max(len(layout.lines)-pageSize, 0))
+}
+
+// keepPrettyCursorOnScreen moves the cursor to the first row on the screen
+// when paging scrolled it off
+func (m *Model) keepPrettyCursorOnScreen() {
+	tree := m.tree()
+	if tree == nil {
+		return
+	}
+	layout := m.prettyLayout()
+	viewport := min(m.prettyViewport, max(len(layout.lines)-m.prettyPageSize(), 0))
+	if start, _ := layout.rowLines(tree.cursor); start >= viewport && start < viewport+m.prettyPageSize() {
+		return
+	}
+	for i, row := range layout.rows {
+		if !row.closing && layout.rowStarts[i] >= viewport {
+			tree.cursor = row.node
+			return
+		}
+	}
+}
+
+// prettyTreeSearchNext moves the cursor to the next value with a match, in
+// the order they're written, expanding what it's in and scrolling it to the
+// top. Collapsed values are searched too.
+func (m *Model) prettyTreeSearchNext(tree *prettyTree, backward bool) {
+	nodes := tree.nodes()
+	current := 0
+	for i, n := range nodes {
+		if n == tree.cursor {
+			current = i
+		}
+	}
+	step := 1
+	if backward {
+		step = -1
+	}
+	for i := current + step; i >= 0 && i < len(nodes); i += step {
+		if m.searchMatches(nodes[i].text(false)) == nil {
+			continue
+		}
+		tree.reveal(nodes[i])
+		tree.cursor = nodes[i]
+		layout := m.prettyLayout()
+		start, _ := layout.rowLines(nodes[i])
+		m.prettyViewport = min(start, max(len(layout.lines)-m.prettyPageSize(), 0))
+		return
+	}
+	m.activeSearch.notFound = true
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pressSpecialKey sends a key that isn't typed as text, like an arrow
+func pressSpecialKey(model Model, key tea.KeyType) Model {
+	newModel, _ := model.Update(tea.KeyMsg{Type: key})
+	return newModel.(Model)
+}
+
+// openPretty opens the pretty view on a request log
+func openPretty(t *testing.T) Model {
+	t.Helper()
+	model := testModel(parseLines(`{"level": "info", "request": {"method": "GET", "headers": {"accept": "*/*", "x-id": "abc"}}, "tags": ["a", "b"], "latency": 1.50}`))
+	model.height = 30
+	model, _ = pressKey(model, " ")
+	if !model.showPretty || model.prettyTree == nil {
+		t.Fatal("Expected the pretty view with a tree")
+	}
+	return model
+}
+
+// TestJSONNodePath tests the jq paths shown for values
+func TestJSONNodePath(t *testing.T) {
+	root, err := parseJSONTree(`{"request": {"headers": {"x-id": "1", "ok_2": "2"}}, "items": [{"id": 1}], "a b": 3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := root.children[0].children[0]
+	tests := []struct {
+		node *jsonNode
+		want string
+	}{
+		{root, "."},
+		{root.children[0], ".request"},
+		{headers.children[0], `.request.headers["x-id"]`},
+		{headers.children[1], ".request.headers.ok_2"},
+		{root.children[1].children[0].children[0], ".items[0].id"},
+		{root.children[2], `.["a b"]`},
+	}
+	for _, tt := range tests {
+		if got := tt.node.path(); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+	}
+
+	array, err := parseJSONTree(`[1, [2]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := array.children[1].children[0].path(); got != ".[1][0]" {
+		t.Errorf("Expected .[1][0], got %s", got)
+	}
+}
+
+// TestPrettyTreeNavigation tests moving around the tree and collapsing and expanding it
+func TestPrettyTreeNavigation(t *testing.T) {
+	model := openPretty(t)
+
+	model, _ = pressKey(model, "j")
+	model = pressSpecialKey(model, tea.KeyTab)
+	if got := model.prettyTree.cursor.path(); got != ".request" {
+		t.Fatalf("Expected Tab to go to the next key, got %s", got)
+	}
+	model = pressSpecialKey(model, tea.KeyRight)
+	model = pressSpecialKey(model, tea.KeyTab)
+	model = pressSpecialKey(model, tea.KeyRight)
+	model = pressSpecialKey(model, tea.KeyTab)
+	if got := model.prettyTree.cursor.path(); got != `.request.headers["x-id"]` {
+		t.Fatalf("Expected to be on the header, got %s", got)
+	}
+	if !strings.Contains(model.View(), `.request.headers["x-id"]`) {
+		t.Error("Expected the path in the status bar")
+	}
+
+	// Left goes to the parent, then collapses it
+	model = pressSpecialKey(model, tea.KeyLeft)
+	model = pressSpecialKey(model, tea.KeyLeft)
+	view := stripANSI(model.View())
+	if strings.Contains(view, `"x-id"`) || !strings.Contains(view, `"headers": {…}`) || !strings.Contains(view, "2 keys") {
+		t.Errorf("Expected the headers collapsed, got:\n%s", view)
+	}
+	model, _ = pressKey(model, "j")
+	if got := model.prettyTree.cursor.path(); got != ".tags" {
+		t.Errorf("Expected to skip what's collapsed, got %s", got)
+	}
+	model = pressSpecialKey(model, tea.KeyShiftTab)
+	if got := model.prettyTree.cursor.path(); got != ".request" {
+		t.Errorf("Expected Shift+Tab to go to the previous key, got %s", got)
+	}
+
+	// Collapsing everything keeps the cursor on what's still shown
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "C")
+	if got := model.prettyTree.cursor.path(); got != ".request" {
+		t.Errorf("Expected the cursor on the collapsed request, got %s", got)
+	}
+	if rows := model.prettyTree.rows(); len(rows) != 6 {
+		t.Errorf("Expected only the top level keys, got %d rows", len(rows))
+	}
+	model, _ = pressKey(model, "E")
+	view = stripANSI(model.View())
+	if !strings.Contains(view, `"accept": "*/*"`) {
+		t.Error("Expected everything expanded")
+	}
+	if !strings.Contains(view, `"latency": 1.50`) {
+		t.Error("Expected numbers as written")
+	}
+}
+
+// TestPrettyTreeSearch tests that searching finds values in collapsed objects
+func TestPrettyTreeSearch(t *testing.T) {
+	model := openPretty(t)
+	model, _ = pressKey(model, " ")
+	model, _ = typeSearch(model, '/', "x-id")
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, "C")
+	model, _ = pressKey(model, "n")
+
+	if got := model.prettyTree.cursor.path(); got != `.request.headers["x-id"]` {
+		t.Fatalf("Expected the match under the cursor, got %s", got)
+	}
+	if !strings.Contains(model.View(), `"x-id": "abc"`) {
+		t.Error("Expected the match expanded and shown")
+	}
+	model, _ = pressKey(model, "n")
+	if !model.activeSearch.notFound {
+		t.Error("Expected no more matches")
+	}
+}
+
+// TestPrettyTreeScrolling tests that the view follows the cursor
+func TestPrettyTreeScrolling(t *testing.T) {
+	model := openPretty(t)
+	model.height = 4
+	for range 5 {
+		model, _ = pressKey(model, "j")
+	}
+	start, end := model.prettyLayout().rowLines(model.prettyTree.cursor)
+	if start < model.prettyViewport || end > model.prettyViewport+model.prettyPageSize() {
+		t.Errorf("Expected the cursor on screen, rows %d-%d with the view at %d", start, end, model.prettyViewport)
+	}
+
+	// Closing and opening again starts over
+	model, _ = pressKey(model, " ")
+	model, _ = pressKey(model, " ")
+	if model.prettyTree.cursor != model.prettyTree.root || model.prettyViewport != 0 {
+		t.Error("Expected the tree to start over")
+	}
+}
+
+// TestPrettyLayoutReused tests that the tree is only laid out again when what's drawn changes
+func TestPrettyLayoutReused(t *testing.T) {
+	model := openPretty(t)
+	model.View()
+	layout := model.prettyTree.layout
+	if layout == nil {
+		t.Fatal("Expected the layout to be kept")
+	}
+	model.View()
+	if model.prettyTree.layout != layout {
+		t.Error("Expected the same layout to be drawn again")
+	}
+
+	model, _ = pressKey(model, "j")
+	if model.prettyTree.layout == layout {
+		t.Error("Expected the layout redone for the cursor")
+	}
+
+	// Collapsing redraws the rows, and so does a new width
+	model, _ = pressKey(model, "j")
+	model = pressSpecialKey(model, tea.KeyLeft)
+	if rows := len(model.prettyLayout().rows); rows != 9 {
+		t.Errorf("Expected the request collapsed to one row, got %d rows", rows)
+	}
+	newModel, _ := model.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	model = newModel.(Model)
+	if view := stripANSI(model.View()); !strings.Contains(view, `"request": {…}`) || model.prettyTree.layoutFor.width != 60 {
+		t.Errorf("Expected the tree laid out again for the width, got:\n%s", view)
+	}
+}
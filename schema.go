@@ -0,0 +1,452 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	schemaChunkSize = 8192  // Lines scanned between progress reports
+	maxFieldValues  = 10000 // Most different values of a field that are counted
+	topValueCount   = 10    // Values shown for a field's top values
+	minFieldPanel   = 40    // Narrowest the field browser is
+)
+
+var (
+	fieldPanelBorderStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#4A90E2"))
+
+	fieldPanelTitleStyle = lipgloss.NewStyle().
+				Bold(true)
+)
+
+// jsonTypes is a set of the types a field was seen with
+type jsonTypes uint8
+
+const (
+	typeString jsonTypes = 1 << iota
+	typeNumber
+	typeBoolean
+	typeNull
+	typeObject
+	typeArray
+)
+
+// jsonTypeNames are the names of the types, as jq's type returns them
+var jsonTypeNames = []string{"string", "number", "boolean", "null", "object", "array"}
+
+func (types jsonTypes) String() string {
+	var names []string
+	for i, name := range jsonTypeNames {
+		if types&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// bigNumber is a number too large for an int, counted by its digits
+type bigNumber string
+
+// fieldInfo is what's known of a field from the lines scanned
+type fieldInfo struct {
+	path     string
+	types    jsonTypes
+	lines    int                 // Lines that have it
+	values   map[interface{}]int // How often each value was seen, strings, numbers, booleans and null only
+	overflow bool                // Whether it had more different values than are counted
+	lastLine int                 // Number of the line it was last counted in, to count each line once
+}
+
+// schema is the catalogue of the fields in the lines scanned
+type schema struct {
+	fields  map[string]*fieldInfo
+	sorted  []*fieldInfo // The fields by path, once scanning is done
+	lines   linesMark    // Lines scanned, to scan only those added since next time
+	scanned int          // JSON lines scanned
+}
+
+// schemaJob scans lines for their fields in the background
+type schemaJob struct {
+	schema  *schema   // Only the job's until it's done
+	lines   []LogLine // Lines being scanned, never modified while the job runs
+	checked int       // Lines scanned so far
+}
+
+// Message with how many lines a schema job has scanned
+type schemaProgressMsg struct {
+	seq      int
+	checked  int
+	finished bool
+}
+
+// newSchema returns an empty catalogue
+func newSchema() *schema {
+	return &schema{fields: make(map[string]*fieldInfo)}
+}
+
+// clone returns a copy of the catalogue that more lines can be added to
+func (s *schema) clone() *schema {
+	c := &schema{fields: make(map[string]*fieldInfo, len(s.fields)), scanned: s.scanned}
+	for path, field := range s.fields {
+		copied := *field
+		copied.values = make(map[interface{}]int, len(field.values))
+		for value, count := range field.values {
+			copied.values[value] = count
+		}
+		c.fields[path] = &copied
+	}
+	return c
+}
+
+// add adds the fields of a line to the catalogue
+func (s *schema) add(line LogLine) {
+	if !line.IsValid {
+		return
+	}
+	s.scanned++
+	s.addValue("", line.data(), line.LineNumber)
+}
+
+// addValue adds a value and everything in it, found at path in a line
+func (s *schema) addValue(path string, value interface{}, lineNumber int) {
+	var field *fieldInfo
+	if path != "" { // The whole line isn't a field
+		field = s.fields[path]
+		if field == nil {
+			field = &fieldInfo{path: path, values: make(map[interface{}]int)}
+			s.fields[path] = field
+		}
+		if field.lastLine != lineNumber {
+			field.lines++
+			field.lastLine = lineNumber
+		}
+	}
+
+	var types jsonTypes
+	switch v := value.(type) {
+	case map[string]interface{}:
+		types = typeObject
+		for key, item := range v {
+			s.addValue(keyPath(path, key), item, lineNumber)
+		}
+	case []interface{}:
+		types = typeArray
+		for _, item := range v {
+			s.addValue(indexPath(path, ""), item, lineNumber)
+		}
+	case string:
+		types = typeString
+	case bool:
+		types = typeBoolean
+	case nil:
+		types = typeNull
+	case *big.Int:
+		types = typeNumber
+		value = bigNumber(v.String())
+	default:
+		types = typeNumber
+	}
+	if field == nil {
+		return
+	}
+	field.types |= types
+	if types&(typeObject|typeArray) != 0 {
+		return
+	}
+	if _, ok := field.values[value]; ok || len(field.values) < maxFieldValues {
+		field.values[value]++
+	} else {
+		field.overflow = true
+	}
+}
+
+// sort lists the fields by path once scanning is done
+func (s *schema) sort() {
+	s.sorted = s.sorted[:0]
+	for _, field := range s.fields {
+		s.sorted = append(s.sorted, field)
+	}
+	slices.SortFunc(s.sorted, func(a, b *fieldInfo) int {
+		return cmp.Compare(a.path, b.path)
+	})
+}
+
+// cardinality returns how many different values a field has, e.g. 12 or 10,000+
+func (f *fieldInfo) cardinality() string {
+	if f.types&(typeObject|typeArray) != 0 && len(f.values) == 0 {
+		return "-"
+	}
+	count := humanize.Comma(int64(len(f.values)))
+	if f.overflow {
+		count += "+"
+	}
+	return count
+}
+
+// valueCount is how often a field had a value
+type valueCount struct {
+	value interface{}
+	count int
+}
+
+// topValues returns a field's most common values, most common first
+func (f *fieldInfo) topValues(n int) []valueCount {
+	counts := make([]valueCount, 0, len(f.values))
+	for value, count := range f.values {
+		counts = append(counts, valueCount{value, count})
+	}
+	slices.SortFunc(counts, func(a, b valueCount) int {
+		if a.count != b.count {
+			return cmp.Compare(b.count, a.count)
+		}
+		return cmp.Compare(formatFieldValue(a.value), formatFieldValue(b.value))
+	})
+	return counts[:min(n, len(counts))]
+}
+
+// formatFieldValue returns a value as it's written in JSON
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case bigNumber:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value) // Infinite numbers, which JSON can't hold
+	}
+	return string(encoded)
+}
+
+// run scans the lines chunk by chunk, reporting after each one
+func (j *schemaJob) run(r jobRun) {
+	for start := 0; ; start += schemaChunkSize {
+		end := min(start+schemaChunkSize, len(j.lines))
+		for _, line := range j.lines[start:end] {
+			j.schema.add(line)
+		}
+		if r.stopped() {
+			return
+		}
+
+		finished := end == len(j.lines)
+		if finished {
+			j.schema.sort()
+		}
+		if !r.send(schemaProgressMsg{seq: r.seq, checked: end, finished: finished}) || finished {
+			return
+		}
+	}
+}
+
+// startSchemaScan scans the lines in memory not in the catalogue yet, all of
+// them when lines were dropped or loaded above since the last scan
+func (m *Model) startSchemaScan() tea.Cmd {
+	if m.schemaJob != nil {
+		return nil
+	}
+	next, lines := newSchema(), m.lines
+	if m.schema != nil {
+		added, ok := m.schema.lines.addedSince(m.lines)
+		if ok && len(added) == 0 {
+			return nil
+		}
+		if ok {
+			next, lines = m.schema.clone(), added
+		}
+	}
+	next.lines = markLines(m.lines)
+
+	j := &schemaJob{schema: next, lines: lines}
+	m.schemaJob = j
+	return m.scanning.start(j.run)
+}
+
+// handleSchemaProgress keeps the catalogue once it's done, and waits for
+// more progress until then
+func (m *Model) handleSchemaProgress(msg schemaProgressMsg) tea.Cmd {
+	if m.scanning.isStale(msg.seq) {
+		return nil // Stopped meanwhile
+	}
+	m.schemaJob.checked = msg.checked
+	if !msg.finished {
+		return m.scanning.wait()
+	}
+	m.scanning.finish()
+	m.schema = m.schemaJob.schema
+	m.schemaJob = nil
+	m.fieldCursor = min(m.fieldCursor, max(len(m.schema.sorted)-1, 0))
+	m.enforceMemoryBudget()
+
+	// Catch up with lines added while scanning
+	if m.fieldBrowserMode {
+		return m.startSchemaScan()
+	}
+	return nil
+}
+
+// stopSchemaJob stops a running scan, waiting until it no longer reads the lines
+func (m *Model) stopSchemaJob() {
+	m.scanning.stop()
+	m.schemaJob = nil
+}
+
+// openFieldBrowser shows the fields of the lines next to them, scanning
+// what hasn't been yet
+func (m *Model) openFieldBrowser() tea.Cmd {
+	m.fieldBrowserMode = true
+	return m.startSchemaScan()
+}
+
+// selectedField returns the field under the browser's cursor, nil before any are known
+func (m Model) selectedField() *fieldInfo {
+	if m.schema == nil || m.fieldCursor >= len(m.schema.sorted) {
+		return nil
+	}
+	return m.schema.sorted[m.fieldCursor]
+}
+
+// addFieldColumn shows the selected field as a column
+func (m *Model) addFieldColumn() {
+	field := m.selectedField()
+	if field == nil || m.hasColumn(field.path) {
+		return
+	}
+	c, err := newColumn(field.path, 0)
+	if err != nil {
+		return // Paths of fields are always valid
+	}
+	m.columns = append(m.columns, m.fitColumn(c))
+}
+
+// filterOnSelectedField closes the browser and opens the filter input with
+// the selected field in it, to be compared with a value. A field inside
+// arrays matches when any of its values does.
+func (m *Model) filterOnSelectedField() tea.Cmd {
+	field := m.selectedField()
+	if field == nil {
+		return nil
+	}
+	m.fieldBrowserMode = false
+	m.filterMode = true
+	m.filterInput = field.path + " == "
+	m.filterCursorPos = len(m.filterInput)
+	if strings.Contains(field.path, "[]") {
+		m.filterInput = "any(" + field.path + "; . == )"
+		m.filterCursorPos = len(m.filterInput) - 1 // The value goes before the closing bracket
+	}
+	return m.updateFilterPreview(m.filterInput)
+}
+
+// fieldPanelWidth returns how wide the field browser is, about half the screen
+func (m Model) fieldPanelWidth() int {
+	return min(max(m.width/2, minFieldPanel), m.width)
+}
+
+// renderFieldBrowser renders the lines with the field browser next to them
+func (m Model) renderFieldBrowser() string {
+	panelWidth := m.fieldPanelWidth()
+	panel := m.fieldPanelLines(panelWidth-2, max(m.height-1, 1)) // Account for the border and status bar
+	statusText := "Fields | ↑/↓ select | c add as column | f filter on it | s stats | t/ENTER top values | b/ESC close"
+	return m.besidePanel(m.getVisibleLines(), panel, panelWidth, statusStyle.Width(m.width-1).Render(statusText))
+}
+
+// besidePanel renders displayLines in the space a panel on the right leaves,
+// the panel next to them and status below
+func (m Model) besidePanel(displayLines []LogLine, panel []string, panelWidth int, status string) string {
+	availableLines := max(m.height-1, 1) // Account for status bar
+
+	// The lines as they'd be shown in the space left
+	linesWidth := m.width - panelWidth
+	left := strings.Split(m.renderLogLines(displayLines, linesWidth), "\n")
+	fit := lipgloss.NewStyle().MaxWidth(linesWidth)
+	border := fieldPanelBorderStyle.Render("│") + " "
+
+	var s strings.Builder
+	for i := 0; i < availableLines; i++ {
+		line := ""
+		if i < len(left) && linesWidth > 0 {
+			line = fit.Render(left[i])
+		}
+		s.WriteString(line + strings.Repeat(" ", max(linesWidth-lipgloss.Width(line), 0)))
+		s.WriteString(border)
+		if i < len(panel) {
+			s.WriteString(panel[i])
+		}
+		s.WriteString("\n")
+	}
+	s.WriteString(status)
+	return s.String()
+}
+
+// fieldPanelLines returns the lines of the field browser, fitted to width
+// and height
+func (m Model) fieldPanelLines(width, height int) []string {
+	clip := func(text string) string {
+		if len([]rune(text)) > width && width > 0 {
+			return fitCell(text, width)
+		}
+		return text
+	}
+
+	title := "Fields"
+	switch {
+	case m.schemaJob != nil && len(m.schemaJob.lines) > 0:
+		title += fmt.Sprintf(" (scanning %d%%)", m.schemaJob.checked*100/len(m.schemaJob.lines))
+	case m.schema != nil:
+		title += fmt.Sprintf(" in %s JSON lines", humanize.Comma(int64(m.schema.scanned)))
+	}
+	panel := []string{fieldPanelTitleStyle.Render(clip(title)), ""}
+	if m.schema == nil {
+		return panel
+	}
+	if len(m.schema.sorted) == 0 {
+		return append(panel, clip("No fields found."))
+	}
+
+	// Keep room for the top values of the selected field
+	field := m.selectedField()
+	var details []string
+	if m.fieldTopValues && field != nil {
+		details = append(details, "", fieldPanelTitleStyle.Render(clip("Top values of "+field.path)))
+		for _, top := range field.topValues(topValueCount) {
+			percent := top.count * 100 / max(field.lines, 1)
+			details = append(details, clip(fmt.Sprintf("%7s %3d%%  %s", humanize.Comma(int64(top.count)), percent, formatFieldValue(top.value))))
+		}
+		if len(field.values) == 0 {
+			details = append(details, clip("  Only objects and arrays"))
+		}
+	}
+	rows := max(height-len(panel)-len(details)-1, 1) // Account for the header
+
+	const statsWidth = 2 + 1 + 12 + 1 + 4 + 1 + 7 // Cursor, types, presence and distinct values
+	pathWidth := max(width-statsWidth, 8)
+	panel = append(panel, clip(fmt.Sprintf("  %s %-12s %4s %7s", fitCell("Path", pathWidth), "Types", "Seen", "Values")))
+
+	start := max(0, m.fieldCursor-rows+1)
+	for i := start; i < len(m.schema.sorted) && i < start+rows; i++ {
+		f := m.schema.sorted[i]
+		prefix := "  "
+		if i == m.fieldCursor {
+			prefix = "> "
+		}
+		percent := f.lines * 100 / max(m.schema.scanned, 1)
+		row := clip(fmt.Sprintf("%s%s %-12s %3d%% %7s", prefix, fitCell(f.path, pathWidth), fitCell(f.types.String(), 12), percent, f.cardinality()))
+		if i == m.fieldCursor {
+			row = selectedLineStyle.UnsetPadding().Render(row)
+		}
+		panel = append(panel, row)
+	}
+	return append(panel, details...)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// browseFields opens the field browser and waits for the scan
+func browseFields(t *testing.T, model Model) Model {
+	t.Helper()
+	model, cmd := pressKey(model, "b")
+	if !model.fieldBrowserMode {
+		t.Fatal("Expected the field browser")
+	}
+	return runCmd(model, cmd)
+}
+
+// TestSchemaCatalogue tests the paths, types, presence and values of fields
+func TestSchemaCatalogue(t *testing.T) {
+	s := newSchema()
+	for i, raw := range []string{
+		`{"level": "info", "x-id": 1, "items": [{"sku": "a"}, {"sku": "b"}], "n": 123456789012345678901234567890}`,
+		`{"level": "error", "x-id": "one", "items": [], "n": 1.5}`,
+		`not json`,
+		`{"level": "info", "x-id": null}`,
+	} {
+		s.add(parseLogLine(i+1, raw))
+	}
+	s.sort()
+
+	if s.scanned != 3 {
+		t.Errorf("Expected 3 JSON lines scanned, got %d", s.scanned)
+	}
+	var paths []string
+	for _, field := range s.sorted {
+		paths = append(paths, field.path)
+	}
+	want := `.["x-id"] .items .items[] .items[].sku .level .n`
+	if strings.Join(paths, " ") != want {
+		t.Fatalf("Expected %s, got %s", want, strings.Join(paths, " "))
+	}
+
+	tests := []struct {
+		path   string
+		types  string
+		lines  int
+		values string
+	}{
+		{`.["x-id"]`, "string|number|null", 3, "3"},
+		{".items", "array", 2, "-"},
+		{".items[]", "object", 1, "-"},
+		{".items[].sku", "string", 1, "2"},
+		{".level", "string", 3, "2"},
+		{".n", "number", 2, "2"},
+	}
+	for _, tt := range tests {
+		field := s.fields[tt.path]
+		if got := field.types.String(); got != tt.types {
+			t.Errorf("%s: expected types %s, got %s", tt.path, tt.types, got)
+		}
+		if field.lines != tt.lines {
+			t.Errorf("%s: expected in %d lines, got %d", tt.path, tt.lines, field.lines)
+		}
+		if got := field.cardinality(); got != tt.values {
+			t.Errorf("%s: expected %s values, got %s", tt.path, tt.values, got)
+		}
+	}
+
+	top := s.fields[".level"].topValues(10)
+	if len(top) != 2 || formatFieldValue(top[0].value) != `"info"` || top[0].count != 2 {
+		t.Errorf("Expected info twice first, got %v", top)
+	}
+	if got := formatFieldValue(s.fields[".n"].topValues(10)[0].value); got != "1.5" {
+		t.Errorf("Expected values in order, got %s first", got)
+	}
+}
+
+// TestSchemaValueLimit tests that fields with very many values stop being counted
+func TestSchemaValueLimit(t *testing.T) {
+	model := testModel(numberedLines(maxFieldValues + 5))
+	model = browseFields(t, model)
+	if got := model.schema.fields[".line"].cardinality(); got != "10,000+" {
+		t.Errorf("Expected the values capped, got %s", got)
+	}
+	if got := model.schema.fields[".even"].cardinality(); got != "2" {
+		t.Errorf("Expected 2 values, got %s", got)
+	}
+}
+
+// TestFieldBrowserScansAddedLines tests that only new lines are scanned when the browser opens again
+func TestFieldBrowserScansAddedLines(t *testing.T) {
+	model := browseFields(t, testModel(traceLines()))
+	if model.linesShared() {
+		t.Error("Expected the lines free once scanned")
+	}
+	first := model.schema
+	if len(first.sorted) != 4 || first.fields[".user.id"].lines != 4 {
+		t.Fatalf("Expected 4 fields, got %d", len(first.sorted))
+	}
+
+	model, _ = pressKey(model, "b")
+	model, cmd := pressKey(model, "b")
+	if cmd != nil || model.schema != first {
+		t.Error("Expected no scan without new lines")
+	}
+	model, _ = pressKey(model, "b")
+
+	line := parseLogLine(6, `{"level": "debug", "span": "s1"}`)
+	model.lines = append(model.lines, line)
+	model.filteredLines = model.lines
+	model, cmd = pressKey(model, "b")
+	if model.schemaJob == nil || len(model.schemaJob.lines) != 1 {
+		t.Fatal("Expected only the added line scanned")
+	}
+	model = runCmd(model, cmd)
+	if model.schema.scanned != 6 || model.schema.fields[".span"] == nil || model.schema.fields[".level"].lines != 6 {
+		t.Errorf("Expected the added line in the catalogue, got %d lines", model.schema.scanned)
+	}
+	if first.fields[".level"].lines != 5 {
+		t.Error("Expected the earlier catalogue unchanged")
+	}
+}
+
+// TestFieldBrowserActions tests adding a field as a column, filtering on it and its top values
+func TestFieldBrowserActions(t *testing.T) {
+	model := browseFields(t, testModel(traceLines()))
+	view := stripANSI(model.View())
+	if !strings.Contains(view, "Fields in 5 JSON lines") || !strings.Contains(view, "> .level") {
+		t.Fatalf("Expected the fields listed, got %q", view)
+	}
+	if !strings.Contains(view, ".user.id") || !strings.Contains(view, "number|null") || !strings.Contains(view, " 80%") {
+		t.Errorf("Expected the types and presence of .user.id, got %q", view)
+	}
+	for _, line := range strings.Split(view, "\n") {
+		if width := len([]rune(line)); width > model.width {
+			t.Errorf("Expected lines to fit the screen, got %d wide: %q", width, line)
+		}
+	}
+
+	model, _ = pressKey(model, "t")
+	view = stripANSI(model.View())
+	if !strings.Contains(view, "Top values of .level") || !strings.Contains(view, "4  80%  \"info\"") {
+		t.Errorf("Expected the top values of .level, got %q", view)
+	}
+
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "c")
+	model, _ = pressKey(model, "c")
+	if len(model.columns) != 1 || model.columns[0].expression != ".trace_id" {
+		t.Fatalf("Expected .trace_id as a column once, got %d columns", len(model.columns))
+	}
+
+	model, _ = pressKey(model, "f")
+	if model.fieldBrowserMode || !model.filterMode || model.filterInput != ".trace_id == " {
+		t.Fatalf("Expected the filter input with the field, got %q", model.filterInput)
+	}
+	model.filterInput += `"b2"`
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = runCmd(newModel.(Model), cmd)
+	if got := visibleLineNumbers(model); len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		t.Errorf("Expected the lines of trace b2, got %v", got)
+	}
+}
+
+// TestFilterOnArrayField tests filtering on a field inside arrays from the browser
+func TestFilterOnArrayField(t *testing.T) {
+	model := browseFields(t, testModel(parseLines(
+		`{"items": [{"sku": "a"}, {"sku": "b"}]}`,
+		`{"items": [{"sku": "c"}]}`,
+		`{"items": []}`,
+	)))
+	for range 2 {
+		model, _ = pressKey(model, "j")
+	}
+	if field := model.selectedField(); field == nil || field.path != ".items[].sku" {
+		t.Fatal("Expected .items[].sku third")
+	}
+
+	model, _ = pressKey(model, "f")
+	if model.filterInput != "any(.items[].sku; . == )" || model.filterCursorPos != len(model.filterInput)-1 {
+		t.Fatalf("Expected a test of every value with the cursor inside, got %q at %d", model.filterInput, model.filterCursorPos)
+	}
+	for _, r := range `"b"` {
+		model, _ = pressKey(model, string(r))
+	}
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = runCmd(newModel.(Model), cmd)
+	if got := visibleLineNumbers(model); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Expected only the line with sku b, got %v", got)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/itchyny/gojq"
+)
+
+// searchChunkSize is how many lines are read at a time when searching lines not in memory
+const searchChunkSize = 1000
+
+var searchMatchStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("#FFD700")).
+	Foreground(lipgloss.Color("#000000"))
+
+// search is the pattern last searched for
+type search struct {
+	query    string
+	regex    bool // Whether query is a regular expression rather than plain text
+	re       *regexp.Regexp
+	backward bool // Whether it was started with ?, so n searches towards the start
+	notFound bool // Whether the last attempt found nothing
+}
+
+// Message with the line a search of lines not in memory found, 0 if none
+type searchResultMsg struct {
+	seq        int
+	lineNumber int
+	err        error
+}
+
+// compileSearch turns what was typed into a pattern. Plain text is matched
+// literally, and both ignore case unless there's an upper case letter in it.
+func compileSearch(query string, regex bool) (*regexp.Regexp, error) {
+	pattern := query
+	if !regex {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if !strings.ContainsFunc(query, unicode.IsUpper) {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// searchText returns a line as it's shown, which is what's searched
+func searchText(viewFilter *gojq.Query, columns []column, line LogLine) string {
+	if len(columns) > 0 && line.IsValid {
+		return tableRow(columns, line)
+	}
+	if viewFilter != nil && line.IsValid {
+		if transformed, _ := transformLine(viewFilter, line.data()); transformed != "" {
+			return transformed
+		}
+	}
+	return line.RawLine
+}
+
+// openSearch opens the search input, towards the start of the file for ?
+func (m *Model) openSearch(backward bool) {
+	m.searchMode = true
+	m.searchInput = ""
+	m.searchBackward = backward
+	m.searchRegex = m.activeSearch != nil && m.activeSearch.regex
+}
+
+// submitSearch searches for what was typed, or repeats the last search when
+// nothing was. The input stays open when the pattern isn't valid.
+func (m *Model) submitSearch() tea.Cmd {
+	if m.searchInput == "" {
+		m.searchMode = false
+		if m.activeSearch == nil {
+			return nil
+		}
+		m.activeSearch.backward = m.searchBackward
+		return m.searchNext(false)
+	}
+
+	re, err := compileSearch(m.searchInput, m.searchRegex)
+	if err != nil {
+		m.inputErr = err
+		return nil
+	}
+	m.searchMode = false
+	m.activeSearch = &search{query: m.searchInput, regex: m.searchRegex, re: re, backward: m.searchBackward}
+	return m.searchNext(false)
+}
+
+// searchNext moves the cursor to the next match, in the direction of the
+// last search or the other one when reverse is set. Lines not in memory are
+// searched in the background.
+func (m *Model) searchNext(reverse bool) tea.Cmd {
+	if m.activeSearch == nil || m.searching.running() {
+		return nil
+	}
+	backward := m.activeSearch.backward != reverse
+
+	visibleLines := m.getVisibleLines()
+	step := 1
+	if backward {
+		step = -1
+	}
+	for i := m.cursor + step; i >= 0 && i < len(visibleLines); i += step {
+		if !visibleLines[i].Separator && m.activeSearch.re.MatchString(searchText(m.viewFilter, m.columns, visibleLines[i])) {
+			m.activeSearch.notFound = false
+			m.restorePositionAfterFilter(visibleLines[i].LineNumber)
+			return nil
+		}
+	}
+
+	// Carry on through the lines that aren't in memory
+	from, to := 0, 0
+	switch {
+	case !backward && m.canSearchBelow():
+		from = m.lastLineNum + 1
+	case backward && m.canSearchAbove():
+		from, to = 1, m.lines[0].LineNumber-1
+	default:
+		m.activeSearch.notFound = true
+		return nil
+	}
+
+	m.showSpinner = true
+	m.spinnerFrame = 0
+	filters := compileFilters(m.filters)
+	for i := range filters {
+		filters[i].failures = nil
+	}
+	s := searchFile{
+		filename:   m.filename,
+		index:      m.index,
+		from:       from,
+		to:         to,
+		backward:   backward,
+		re:         m.activeSearch.re,
+		viewFilter: m.viewFilter,
+		columns:    append([]column(nil), m.columns...), // Columns are moved and resized in place
+		filters:    filters,
+		invalid:    m.invalidLines,
+	}
+	return tea.Batch(spinnerTickCmd(), m.searching.start(func(r jobRun) {
+		lineNumber, err := s.find(r)
+		r.send(searchResultMsg{seq: r.seq, lineNumber: lineNumber, err: err})
+	}))
+}
+
+// prettySearchNext moves the pretty view to the next value with a match, or
+// line for lines that aren't JSON, in the direction of the last search or the
+// other one when reverse is set
+func (m *Model) prettySearchNext(reverse bool) {
+	if m.activeSearch == nil {
+		return
+	}
+	backward := m.activeSearch.backward != reverse
+	m.activeSearch.notFound = false
+	if tree := m.tree(); tree != nil {
+		m.prettyTreeSearchNext(tree, backward)
+		return
+	}
+
+	_, matchLines := m.prettyLines()
+	current := m.prettyViewport
+	if maxScroll := m.calculatePrettyMaxScroll(); current > maxScroll {
+		current = maxScroll
+	}
+	if backward {
+		for i := len(matchLines) - 1; i >= 0; i-- {
+			if matchLines[i] < current {
+				m.prettyViewport = matchLines[i]
+				return
+			}
+		}
+	} else {
+		for _, line := range matchLines {
+			if line > current {
+				m.prettyViewport = min(line, m.calculatePrettyMaxScroll())
+				return
+			}
+		}
+	}
+	m.activeSearch.notFound = true
+}
+
+// canSearchBelow reports whether there are lines after those in memory that can be read
+func (m Model) canSearchBelow() bool {
+	return !m.isFileFullyLoaded && m.stream == nil && len(m.sources) == 0 && !m.fileReplaced
+}
+
+// canSearchAbove reports whether there are lines before those in memory that
+// can be read, and jumped to once found
+func (m Model) canSearchAbove() bool {
+	return m.hasLinesAbove() && m.canJump()
+}
+
+// cancelSearch stops searching lines not in memory
+func (m *Model) cancelSearch() {
+	m.searching.stop()
+	m.showSpinner = false
+}
+
+// handleSearchResult goes to the line found in the file, if any
+func (m *Model) handleSearchResult(msg searchResultMsg) tea.Cmd {
+	if m.searching.isStale(msg.seq) {
+		return nil
+	}
+	m.searching.finish()
+	m.showSpinner = false
+	if msg.err != nil || msg.lineNumber == 0 {
+		m.activeSearch.notFound = true
+		return nil
+	}
+	m.activeSearch.notFound = false
+	return m.goToLine(msg.lineNumber)
+}
+
+// searchFile describes a search of lines not in memory
+type searchFile struct {
+	filename   string
+	index      *lineIndex
+	from, to   int // Lines to search, to the end of the file when to is 0
+	backward   bool
+	re         *regexp.Regexp
+	viewFilter *gojq.Query
+	columns    []column
+	filters    []Filter
+	invalid    invalidLineMode
+}
+
+// find reads lines from the file looking for a visible line that matches,
+// the first one found or the last one when searching backwards, 0 if none
+func (s searchFile) find(r jobRun) (int, error) {
+	file, err := reopenLogFile(s.filename, s.index, s.from)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	found := 0
+	for lineNumber := s.from; s.to == 0 || lineNumber <= s.to; {
+		if r.stopped() {
+			return 0, nil
+		}
+
+		lines, atEOF, err := file.readLines(lineNumber, searchChunkSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, line := range lines {
+			if s.to > 0 && line.LineNumber > s.to {
+				break
+			}
+			if linePassesFilters(s.filters, s.invalid, line) && s.re.MatchString(searchText(s.viewFilter, s.columns, line)) {
+				found = line.LineNumber
+				if !s.backward {
+					return found, nil
+				}
+			}
+		}
+		if atEOF || len(lines) == 0 {
+			break
+		}
+		lineNumber += len(lines)
+	}
+	return found, nil
+}
+
+// highlightMatches renders text with the spans in matches picked out and the
+// rest in base
+func highlightMatches(text string, matches [][]int, base lipgloss.Style) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		if match[0] == match[1] || match[0] < last {
+			continue // Empty matches have nothing to show
+		}
+		b.WriteString(base.Render(text[last:match[0]]))
+		b.WriteString(searchMatchStyle.Render(text[match[0]:match[1]]))
+		last = match[1]
+	}
+	b.WriteString(base.Render(text[last:]))
+	return b.String()
+}
+
+// searchMatches returns where the last search matches text, nil without one
+func (m Model) searchMatches(text string) [][]int {
+	if m.activeSearch == nil {
+		return nil
+	}
+	return m.activeSearch.re.FindAllStringIndex(text, -1)
+}
+
+// searchStatus returns the last search for the status bar
+func (m Model) searchStatus() string {
+	if m.activeSearch == nil {
+		return ""
+	}
+	prefix := "/"
+	if m.activeSearch.backward {
+		prefix = "?"
+	}
+	status := fmt.Sprintf(" | %s%s", prefix, m.activeSearch.query)
+	switch {
+	case m.searching.running():
+		status += " (searching, Esc to stop)"
+	case m.activeSearch.notFound:
+		status += " (not found)"
+	}
+	return status
+}
+
+// searchPrompt returns the search input's text
+func (m Model) searchPrompt() string {
+	prefix := "/"
+	if m.searchBackward {
+		prefix = "?"
+	}
+	mode := "text"
+	if m.searchRegex {
+		mode = "regex"
+	}
+	return fmt.Sprintf("Search [%s, Ctrl+R to switch] %s%s", mode, prefix, m.searchInput)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeSearch searches through the search input, / or ? being the key that opens it
+func typeSearch(model Model, key rune, query string) (Model, tea.Cmd) {
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+	model = newModel.(Model)
+	model, _ = typeKeys(model, query)
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return newModel.(Model), cmd
+}
+
+// pressKey sends a single key to the model
+func pressKey(model Model, key string) (Model, tea.Cmd) {
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return newModel.(Model), cmd
+}
+
+// TestSearchLoadedLines tests moving between matches in the lines in memory
+func TestSearchLoadedLines(t *testing.T) {
+	model := testModel(numberedLines(100))
+
+	model, cmd := typeSearch(model, '/', `"LINE": 5`)
+	if cmd != nil {
+		t.Fatal("Expected no background search when the match is in memory")
+	}
+	if model.searchMode {
+		t.Fatal("Expected the search input to close")
+	}
+	// Upper case makes the search match case, which finds nothing
+	if !model.activeSearch.notFound || model.cursorLineNumber() != 1 {
+		t.Errorf("Expected no match for a case sensitive search, got line %d", model.cursorLineNumber())
+	}
+
+	model, _ = typeSearch(model, '/', `"line": 5`)
+	if got := model.cursorLineNumber(); got != 5 {
+		t.Fatalf("Expected the first match on line 5, got %d", got)
+	}
+	if !strings.Contains(model.View(), `/"line": 5`) {
+		t.Error("Expected the search in the status bar")
+	}
+
+	model, _ = pressKey(model, "n")
+	if got := model.cursorLineNumber(); got != 50 {
+		t.Errorf("Expected n to go to line 50, got %d", got)
+	}
+	model, _ = pressKey(model, "N")
+	if got := model.cursorLineNumber(); got != 5 {
+		t.Errorf("Expected N to go back to line 5, got %d", got)
+	}
+	model, _ = pressKey(model, "N")
+	if got := model.cursorLineNumber(); got != 5 || !model.activeSearch.notFound {
+		t.Errorf("Expected nothing before line 5, got line %d", got)
+	}
+	if !strings.Contains(model.View(), "(not found)") {
+		t.Error("Expected the status bar to say nothing was found")
+	}
+
+	// ? searches backwards, and n carries on backwards
+	model.restorePositionAfterFilter(100)
+	model, _ = typeSearch(model, '?', `"line": 5`)
+	if got := model.cursorLineNumber(); got != 59 {
+		t.Errorf("Expected ? to find line 59, got %d", got)
+	}
+	model, _ = pressKey(model, "n")
+	if got := model.cursorLineNumber(); got != 58 {
+		t.Errorf("Expected n to go on to line 58, got %d", got)
+	}
+
+	// Only lines that pass the filters are searched
+	filter := mustFilter(t, ".even")
+	model.filters = []Filter{filter}
+	model.applyFilters()
+	model.restorePositionAfterFilter(2)
+	model, _ = typeSearch(model, '/', `"line": 5`)
+	if got := model.cursorLineNumber(); got != 50 {
+		t.Errorf("Expected the first even match on line 50, got %d", got)
+	}
+}
+
+// TestSearchRegex tests switching to a regex and that one that doesn't
+// compile keeps the input open
+func TestSearchRegex(t *testing.T) {
+	model := testModel(numberedLines(100))
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	model = newModel.(Model)
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	model = newModel.(Model)
+	if !model.searchRegex || !strings.Contains(model.View(), "regex") {
+		t.Fatal("Expected Ctrl+R to switch to a regex")
+	}
+
+	model, _ = typeKeys(model, `"line": (`)
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if !model.searchMode || model.inputErr == nil {
+		t.Fatal("Expected the input to stay open with the error")
+	}
+	if !strings.Contains(model.View(), "Error:") {
+		t.Error("Expected the error to be shown")
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model = newModel.(Model)
+	model, _ = typeKeys(model, `7\d,`)
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if got := model.cursorLineNumber(); got != 70 {
+		t.Errorf("Expected the regex to find line 70, got %d", got)
+	}
+
+	// An empty search repeats the last one
+	model, _ = typeSearch(model, '/', "")
+	if got := model.cursorLineNumber(); got != 71 {
+		t.Errorf("Expected the last search to find line 71, got %d", got)
+	}
+}
+
+// TestSearchView tests that lines are searched as the view shows them
+func TestSearchView(t *testing.T) {
+	model := testModel(numberedLines(100))
+	query, err := parseViewExpression(`"entry \(.line)"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.viewFilter = query
+
+	model, _ = typeSearch(model, '/', "entry 42")
+	if got := model.cursorLineNumber(); got != 42 {
+		t.Errorf("Expected the transformed text to be searched, got line %d", got)
+	}
+	if matches := model.searchMatches(searchText(model.viewFilter, nil, model.filteredLines[41])); len(matches) != 1 {
+		t.Errorf("Expected one match to highlight, got %d", len(matches))
+	}
+}
+
+// TestSearchUnloadedLines tests that lines not in memory are searched in both directions
+func TestSearchUnloadedLines(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+
+	model, cmd := typeSearch(model, '/', `number 4321"`)
+	if !model.searching.running() || cmd == nil {
+		t.Fatal("Expected the rest of the file to be searched in the background")
+	}
+	if !strings.Contains(model.View(), "(searching") {
+		t.Error("Expected the status bar to show the search")
+	}
+	model = runCmd(model, cmd)
+	if model.searching.running() || model.showSpinner {
+		t.Fatal("Expected the search to be done")
+	}
+	if got := model.cursorLineNumber(); got != 4321 {
+		t.Fatalf("Expected the cursor on line 4321, got %d", got)
+	}
+	if model.lines[0].LineNumber == 1 {
+		t.Fatal("Expected the match to be reached through the index")
+	}
+
+	model, cmd = typeSearch(model, '?', `number 12"`)
+	if cmd == nil {
+		t.Fatal("Expected the lines above the window to be searched")
+	}
+	model = runCmd(model, cmd)
+	if got := model.cursorLineNumber(); got != 12 {
+		t.Errorf("Expected the cursor on line 12, got %d", got)
+	}
+
+	model, cmd = typeSearch(model, '/', "no such message")
+	model = runCmd(model, cmd)
+	if !model.activeSearch.notFound || model.cursorLineNumber() != 12 {
+		t.Errorf("Expected nothing found and the cursor left on line 12, got %d", model.cursorLineNumber())
+	}
+
+	// A last line without a newline is searched too
+	appendToFile(t, model.filename, `{"msg": "unterminated"}`)
+	model, cmd = typeSearch(model, '/', "unterminated")
+	model = runCmd(model, cmd)
+	if got := model.cursorLineNumber(); got != 5001 {
+		t.Errorf("Expected the cursor on the last line, got %d", got)
+	}
+}
+
+// TestSearchCancel tests that Esc stops a search of lines not in memory
+func TestSearchCancel(t *testing.T) {
+	model := openIndexed(t, testModel(nil), 5000)
+	model, cmd := typeSearch(model, '/', "no such message")
+
+	newModel, quit := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if quit != nil || model.searching.running() {
+		t.Fatal("Esc while searching should stop it, not quit")
+	}
+	for _, msg := range runBatch(cmd) {
+		newModel, _ = model.Update(msg)
+		model = newModel.(Model)
+	}
+	if model.activeSearch.notFound {
+		t.Error("Expected the cancelled search's result to be ignored")
+	}
+}
+
+// TestSearchPrettyView tests that matches are picked out in the pretty view
+// and n scrolls to them
+func TestSearchPrettyView(t *testing.T) {
+	model := testModel(numberedLines(1))
+	model.height = 3
+	line := parseLogLine(1, `{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6}`)
+	model.lines = []LogLine{line}
+	model.filteredLines = model.lines
+
+	model, _ = typeSearch(model, '/', `"e"`)
+	model, _ = pressKey(model, " ")
+	if !model.showPretty {
+		t.Fatal("Expected the pretty view")
+	}
+
+	_, matchLines := model.prettyLines()
+	if len(matchLines) != 1 || matchLines[0] != 5 {
+		t.Fatalf("Expected a match on the line with e, got %v", matchLines)
+	}
+	model, _ = pressKey(model, "n")
+	if model.prettyViewport != 5 {
+		t.Errorf("Expected n to scroll to the match, viewport at %d", model.prettyViewport)
+	}
+	if !strings.Contains(model.View(), `"e": 5`) {
+		t.Error("Expected the match to be shown")
+	}
+}
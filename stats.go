@@ -0,0 +1,341 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+	"github.com/itchyny/gojq"
+)
+
+const (
+	statsCancelInterval = 4096 // Lines looked at between checks for being stopped
+	histogramBins       = 10
+	minStatsPanel       = 44 // Narrowest the stats panel is
+)
+
+var statsBarStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7CCD7C"))
+
+// percentiles are the percentiles shown for numbers
+var percentiles = []float64{50, 90, 95, 99}
+
+// fieldStats are the figures for a field over some lines
+type fieldStats struct {
+	lines    linesMark      // Lines the figures are for
+	total    int            // JSON lines looked at
+	present  int            // Lines where the field has a value other than null
+	values   map[string]int // How often each value was seen, as JSON
+	overflow bool           // Whether it had more different values than are counted
+	numbers  []float64      // Every number seen, in order
+}
+
+// statsPanel is the field whose figures are shown next to the lines
+type statsPanel struct {
+	path   string // jq path of the field
+	query  *gojq.Query
+	result *fieldStats // Figures last worked out, nil before the first
+	edited bool        // Whether a line shown was changed in place since, which its mark doesn't show
+
+	computing backgroundJob // Works the figures out
+	pending   linesMark     // Lines the figures being worked out are for
+}
+
+// Message with the figures for the lines shown
+type statsMsg struct {
+	seq    int
+	result *fieldStats
+}
+
+// number returns a decoded JSON number as a float, false for other values
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// computeFieldStats works out the figures for a field over lines, or returns
+// false when stopped first
+func computeFieldStats(query *gojq.Query, lines []LogLine, cancel <-chan struct{}) (*fieldStats, bool) {
+	stats := &fieldStats{lines: markLines(lines), values: make(map[string]int)}
+	for i, line := range lines {
+		if i%statsCancelInterval == 0 {
+			select {
+			case <-cancel:
+				return nil, false
+			default:
+			}
+		}
+		if !line.IsValid || line.Context {
+			continue
+		}
+		stats.total++
+
+		// Paths through arrays, like .items[].sku, have a value per element
+		present := false
+		iter := query.Run(line.data())
+		for {
+			value, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if _, isErr := value.(error); isErr || value == nil {
+				continue
+			}
+			present = true
+			if n, ok := number(value); ok {
+				stats.numbers = append(stats.numbers, n)
+			}
+			key := formatFieldValue(value)
+			if _, ok := stats.values[key]; ok || len(stats.values) < maxFieldValues {
+				stats.values[key]++
+			} else {
+				stats.overflow = true
+			}
+		}
+		if present {
+			stats.present++
+		}
+	}
+	slices.Sort(stats.numbers)
+	return stats, true
+}
+
+// topValues returns the most common values, most common first
+func (s *fieldStats) topValues(n int) []valueCount {
+	counts := make([]valueCount, 0, len(s.values))
+	for value, count := range s.values {
+		counts = append(counts, valueCount{value, count})
+	}
+	slices.SortFunc(counts, func(a, b valueCount) int {
+		if a.count != b.count {
+			return cmp.Compare(b.count, a.count)
+		}
+		return cmp.Compare(a.value.(string), b.value.(string))
+	})
+	return counts[:min(n, len(counts))]
+}
+
+// mean returns the average of the numbers
+func (s *fieldStats) mean() float64 {
+	sum := 0.0
+	for _, n := range s.numbers {
+		sum += n
+	}
+	return sum / float64(len(s.numbers))
+}
+
+// percentile returns the number that p percent of the numbers are at or
+// below, by nearest rank
+func (s *fieldStats) percentile(p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(s.numbers))))
+	return s.numbers[max(rank-1, 0)]
+}
+
+// histogram counts the numbers in bins of equal width between the smallest
+// and largest, returning where each bin starts and how many are in it
+func (s *fieldStats) histogram(bins int) ([]float64, []int) {
+	lo, hi := s.numbers[0], s.numbers[len(s.numbers)-1]
+	if lo == hi || math.IsInf(hi-lo, 0) {
+		return []float64{lo}, []int{len(s.numbers)}
+	}
+	width := (hi - lo) / float64(bins)
+	starts, counts := make([]float64, bins), make([]int, bins)
+	for i := range starts {
+		starts[i] = lo + float64(i)*width
+	}
+	for _, n := range s.numbers {
+		counts[min(int((n-lo)/width), bins-1)]++
+	}
+	return starts, counts
+}
+
+// formatStat returns a number with thousands separators and at most two decimals
+func formatStat(n float64) string {
+	if math.IsInf(n, 0) || math.IsNaN(n) || math.Abs(n) >= 1e15 {
+		return fmt.Sprintf("%g", n)
+	}
+	return humanize.Commaf(math.Round(n*100) / 100)
+}
+
+// bar returns a bar as long as count is of most, out of width
+func bar(count, most, width int) string {
+	if most == 0 || width < 1 {
+		return ""
+	}
+	return statsBarStyle.Render(strings.Repeat("█", max(count*width/most, 1)))
+}
+
+// openStats shows the figures for the field at a jq path next to the lines
+func (m *Model) openStats(path string) tea.Cmd {
+	query, err := parseViewExpression(path)
+	if err != nil {
+		return nil // Paths of fields are always valid
+	}
+	m.closeStats()
+	if path != m.stats.path {
+		m.stats.result = nil
+	}
+	m.stats.path = path
+	m.stats.query = query
+	m.showStats = true
+	return m.refreshStats()
+}
+
+// closeStats hides the panel, keeping the field to open it again with %
+func (m *Model) closeStats() {
+	m.stats.computing.stop()
+	m.showStats = false
+}
+
+// toggleStats shows or hides the panel for the last field, or opens the
+// field browser to pick one when there's none yet
+func (m *Model) toggleStats() tea.Cmd {
+	switch {
+	case m.showStats:
+		m.closeStats()
+		return nil
+	case m.stats.path == "":
+		return m.openFieldBrowser()
+	}
+	return m.openStats(m.stats.path)
+}
+
+// refreshStats works the figures out again in the background when the lines
+// shown changed since they were last worked out, or started being. It's
+// called after every message, as any of them can change the lines shown.
+func (m *Model) refreshStats() tea.Cmd {
+	visibleLines := m.getVisibleLines()
+	mark := markLines(visibleLines)
+	switch {
+	case m.stats.edited:
+	case m.stats.computing.running() && m.stats.pending == mark:
+		return nil
+	case !m.stats.computing.running() && m.stats.result != nil && m.stats.result.lines == mark:
+		return nil
+	}
+	m.stats.edited = false
+	m.stats.pending = mark
+
+	query := m.stats.query
+	return m.stats.computing.start(func(r jobRun) {
+		if result, ok := computeFieldStats(query, visibleLines, r.cancel); ok {
+			r.send(statsMsg{seq: r.seq, result: result})
+		}
+	})
+}
+
+// handleStats shows the figures, unless the panel closed or the lines shown
+// changed meanwhile
+func (m *Model) handleStats(msg statsMsg) {
+	if m.stats.computing.isStale(msg.seq) {
+		return
+	}
+	m.stats.computing.finish()
+	m.stats.result = msg.result
+	m.enforceMemoryBudget()
+}
+
+// statsPanelWidth returns how wide the stats panel is
+func (m Model) statsPanelWidth() int {
+	return min(max(m.width*2/5, minStatsPanel), m.width)
+}
+
+// renderStats renders the lines with the stats panel next to them
+func (m Model) renderStats() string {
+	// The status bar of the lines, which shows any input being typed
+	displayLines := m.getVisibleLines()
+	panelWidth := m.statsPanelWidth()
+	panel := m.statsPanelLines(panelWidth-2, max(m.height-1, 1))
+	return m.besidePanel(displayLines, panel, panelWidth, m.renderStatusBar(displayLines))
+}
+
+// statsPanelLines returns the lines of the stats panel, fitted to width and height
+func (m Model) statsPanelLines(width, height int) []string {
+	clip := func(text string) string {
+		if len([]rune(text)) > width && width > 0 {
+			return fitCell(text, width)
+		}
+		return text
+	}
+
+	title := "Stats of " + m.stats.path
+	if m.stats.computing.running() {
+		title += " (updating)"
+	}
+	panel := []string{fieldPanelTitleStyle.Render(clip(title))}
+	s := m.stats.result
+	if s == nil {
+		return panel
+	}
+	panel = append(panel, clip(fmt.Sprintf("In %s of %s JSON lines shown (%d%%)",
+		humanize.Comma(int64(s.present)), humanize.Comma(int64(s.total)), s.present*100/max(s.total, 1))))
+	if s.present == 0 {
+		return panel
+	}
+
+	// Most common values, with bars relative to the most common
+	top := s.topValues(topValueCount)
+	heading := "Top values"
+	if s.overflow {
+		heading += " (of the first 10,000 different)"
+	}
+	panel = append(panel, "", fieldPanelTitleStyle.Render(clip(heading)))
+	valueWidth := 0
+	for _, vc := range top {
+		valueWidth = max(valueWidth, len([]rune(vc.value.(string))))
+	}
+	valueWidth = min(valueWidth, width/2)
+	values := 0
+	for _, count := range s.values {
+		values += count
+	}
+	for _, vc := range top {
+		prefix := fmt.Sprintf("%s %7s %3d%% ", fitCell(vc.value.(string), valueWidth), humanize.Comma(int64(vc.count)), vc.count*100/values)
+		panel = append(panel, clip(prefix)+bar(vc.count, top[0].count, width-len([]rune(prefix))))
+	}
+
+	if len(s.numbers) == 0 {
+		return panel[:min(len(panel), height)]
+	}
+
+	// Spread of the numbers
+	panel = append(panel, "", fieldPanelTitleStyle.Render(clip(fmt.Sprintf("Numbers (%s)", humanize.Comma(int64(len(s.numbers)))))))
+	panel = append(panel, clip(fmt.Sprintf("min %s  max %s  mean %s",
+		formatStat(s.numbers[0]), formatStat(s.numbers[len(s.numbers)-1]), formatStat(s.mean()))))
+	var ranks []string
+	for _, p := range percentiles {
+		ranks = append(ranks, fmt.Sprintf("p%g %s", p, formatStat(s.percentile(p))))
+	}
+	panel = append(panel, clip(strings.Join(ranks, "  ")))
+
+	starts, counts := s.histogram(histogramBins)
+	labelWidth := 0
+	for _, start := range starts {
+		labelWidth = max(labelWidth, len(formatStat(start)))
+	}
+	most := slices.Max(counts)
+	panel = append(panel, "")
+	for i, start := range starts {
+		prefix := fmt.Sprintf("≥ %*s %7s ", labelWidth, formatStat(start), humanize.Comma(int64(counts[i])))
+		row := clip(prefix)
+		if counts[i] > 0 {
+			row += bar(counts[i], most, width-len([]rune(prefix)))
+		}
+		panel = append(panel, row)
+	}
+	return panel[:min(len(panel), height)]
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// requestLines returns 100 requests taking 1 to 100ms, one in ten of them
+// failing
+func requestLines() []LogLine {
+	var lines []LogLine
+	for i := 1; i <= 100; i++ {
+		status := 200
+		if i%10 == 0 {
+			status = 500
+		}
+		lines = append(lines, parseLogLine(i, fmt.Sprintf(`{"status": %d, "duration_ms": %d, "path": "/api"}`, status, i)))
+	}
+	return lines
+}
+
+// TestFieldStats tests the counts and the spread of numbers
+func TestFieldStats(t *testing.T) {
+	model := testModel(requestLines())
+	model.lines = append(model.lines,
+		parseLogLine(101, `not json`),
+		parseLogLine(102, `{"status": null, "items": [{"duration_ms": 1.5}, {"duration_ms": 1000}]}`))
+
+	query, err := parseViewExpression(".status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, ok := computeFieldStats(query, model.lines, nil)
+	if !ok {
+		t.Fatal("Expected the stats")
+	}
+	if stats.total != 101 || stats.present != 100 {
+		t.Errorf("Expected the field in 100 of 101 lines, got %d of %d", stats.present, stats.total)
+	}
+	top := stats.topValues(10)
+	if len(top) != 2 || top[0].value != "200" || top[0].count != 90 || top[1].value != "500" {
+		t.Errorf("Expected 200 then 500, got %v", top)
+	}
+
+	query, _ = parseViewExpression(".duration_ms")
+	stats, _ = computeFieldStats(query, model.lines[:100], nil)
+	want := map[float64]float64{50: 50, 90: 90, 95: 95, 99: 99, 100: 100}
+	for p, n := range want {
+		if got := stats.percentile(p); got != n {
+			t.Errorf("Expected p%g to be %g, got %g", p, n, got)
+		}
+	}
+	if stats.mean() != 50.5 {
+		t.Errorf("Expected a mean of 50.5, got %g", stats.mean())
+	}
+	starts, counts := stats.histogram(10)
+	if starts[0] != 1 || formatStat(starts[9]) != "90.1" || counts[0] != 10 || counts[9] != 10 {
+		t.Errorf("Expected 10 even bins, got %v %v", starts, counts)
+	}
+
+	// Every element of an array counts
+	query, _ = parseViewExpression(".items[].duration_ms")
+	stats, _ = computeFieldStats(query, model.lines, nil)
+	if stats.present != 1 || len(stats.numbers) != 2 || stats.numbers[1] != 1000 {
+		t.Errorf("Expected both elements counted once per line, got %d lines, %v", stats.present, stats.numbers)
+	}
+	if got := formatStat(stats.mean()); got != "500.75" {
+		t.Errorf("Expected the mean with two decimals, got %s", got)
+	}
+}
+
+// TestStatsPanel tests showing the stats next to the lines and following filters
+func TestStatsPanel(t *testing.T) {
+	model := browseFields(t, testModel(requestLines()))
+	model, _ = pressKey(model, "j")
+	model, _ = pressKey(model, "j")
+	model, cmd := pressKey(model, "s")
+	if model.fieldBrowserMode || !model.showStats || model.stats.path != ".status" {
+		t.Fatalf("Expected the stats of .status, got %q", model.stats.path)
+	}
+	model = runCmd(model, cmd)
+
+	view := stripANSI(model.View())
+	for _, want := range []string{"Stats of .status", "In 100 of 100 JSON lines shown (100%)", "200      90  90% ███", "p50 200  p90 200  p95 500  p99 500"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the panel, got %q", want, view)
+		}
+	}
+	if !strings.Contains(view, `{"status": 200`) {
+		t.Error("Expected the lines next to the panel")
+	}
+	for _, line := range strings.Split(view, "\n") {
+		if width := len([]rune(line)); width > model.width {
+			t.Errorf("Expected lines to fit the screen, got %d wide: %q", width, line)
+		}
+	}
+
+	// Nothing to work out again, or check later, while the lines stay the same
+	if _, cmd := model.Update(spinnerTickMsg{}); model.stats.computing.running() || cmd != nil {
+		t.Error("Expected nothing to do while the lines stay the same")
+	}
+
+	// Filtering with the panel open updates it
+	model, cmd = typeFilter(model, ".duration_ms > 50")
+	model = runCmd(model, cmd)
+	if !model.showStats {
+		t.Fatal("Expected the panel to stay open")
+	}
+	if view := stripANSI(model.View()); !strings.Contains(view, "In 50 of 50 JSON lines shown") {
+		t.Errorf("Expected the stats of the filtered lines, got %q", view)
+	}
+
+	// Figures still being worked out when the panel is hidden are ignored
+	model.stats.edited = true
+	model.refreshStats()
+	seq := model.stats.computing.seq
+	model, _ = pressKey(model, "%")
+	if model.showStats || model.stats.computing.running() {
+		t.Fatal("Expected % to hide the panel and stop working the figures out")
+	}
+	model.handleStats(statsMsg{seq: seq, result: &fieldStats{}})
+	if model.stats.result.total != 50 {
+		t.Error("Expected stale figures to be ignored")
+	}
+	model, _ = pressKey(model, "%")
+	if !model.showStats || model.stats.computing.running() {
+		t.Error("Expected the panel back without working the same lines out again")
+	}
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if model.showStats || cmd != nil {
+		t.Error("Expected Esc to close the panel, not quit")
+	}
+}
+
+// TestStatsFromPrettyView tests showing the stats of the value under the pretty view's cursor
+func TestStatsFromPrettyView(t *testing.T) {
+	model := testModel(requestLines())
+	model.height = 30 // Room for the histogram
+	model = openField(t, model, ".duration_ms")
+	model, cmd := pressKey(model, "%")
+	if model.showPretty || model.stats.path != ".duration_ms" {
+		t.Fatalf("Expected the stats of .duration_ms, got %q", model.stats.path)
+	}
+	model = runCmd(model, cmd)
+	view := stripANSI(model.View())
+	for _, want := range []string{"Numbers (100)", "min 1  max 100  mean 50.5", "≥    1      10 "} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the panel, got %q", want, view)
+		}
+	}
+
+	// Without a field yet, % opens the field browser to pick one
+	model = testModel(requestLines())
+	model, _ = pressKey(model, "%")
+	if !model.fieldBrowserMode {
+		t.Error("Expected the field browser")
+	}
+	model.cleanup()
+}
+
+// TestStatsFollowNewLines tests that the stats are worked out again when
+// lines arrive or are finished, and only then
+func TestStatsFollowNewLines(t *testing.T) {
+	model := openField(t, testModel(requestLines()), ".status")
+	model, cmd := pressKey(model, "%")
+	model = runCmd(model, cmd)
+
+	// The last line, shown unterminated, is finished in place
+	newModel, cmd := model.Update(followMsg{
+		finished:        []LogLine{parseLogLine(1, `{"status": 404}`)},
+		firstLineNumber: model.lastLineNum + 1,
+	})
+	model = runCmd(newModel.(Model), cmd)
+	if view := stripANSI(model.View()); !strings.Contains(view, "404       1   1%") {
+		t.Errorf("Expected the finished line in the stats, got %q", view)
+	}
+
+	// Lines arriving while following
+	newModel, cmd = model.Update(followMsg{
+		newLines:        []LogLine{parseLogLine(101, `{"status": 404}`)},
+		firstLineNumber: model.lastLineNum + 1,
+	})
+	model = runCmd(newModel.(Model), cmd)
+	if view := stripANSI(model.View()); !strings.Contains(view, "In 101 of 101 JSON lines shown") {
+		t.Errorf("Expected the new line in the stats, got %q", view)
+	}
+
+	// A check that found nothing new leaves them be
+	newModel, cmd = model.Update(followMsg{firstLineNumber: model.lastLineNum + 1})
+	if model = newModel.(Model); model.stats.computing.running() || cmd != nil {
+		t.Error("Expected nothing to work out again without changes")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// streamBatchSize caps how many buffered lines are delivered in one message
+const streamBatchSize = 1000
+
+// stdinDisplayName is shown in the status bar when reading from stdin
+const stdinDisplayName = "<stdin>"
+
+// Message sent once a stream has reached EOF or failed
+type streamEndedMsg struct {
+	err error
+}
+
+// lineStream reads lines from a non-seekable source in the background
+type lineStream struct {
+	lines chan LogLine
+	err   error // Read error, only valid once lines has been closed
+}
+
+// isStreamSource reports whether filename must be streamed rather than loaded
+func isStreamSource(filename string) bool {
+	if filename == "-" {
+		return true
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeNamedPipe != 0
+}
+
+// isStdinPiped reports whether stdin is connected to a pipe or file instead of a terminal
+func isStdinPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// streamDisplayName returns the name shown in the status bar for a stream source
+func streamDisplayName(filename string) string {
+	if filename == "-" {
+		return stdinDisplayName
+	}
+	return filename
+}
+
+// startLineStream starts reading lines from stdin ("-") or a named pipe
+func startLineStream(filename string) *lineStream {
+	return startReaderStream(func() (io.ReadCloser, error) {
+		if filename == "-" {
+			return os.Stdin, nil
+		}
+		// Opening a named pipe blocks until a writer connects, which is why
+		// this happens on the background goroutine
+		return os.Open(filename)
+	})
+}
+
+// startReaderStream starts a goroutine that reads lines from the reader returned by open
+func startReaderStream(open func() (io.ReadCloser, error)) *lineStream {
+	s := &lineStream{
+		lines: make(chan LogLine, streamBatchSize),
+	}
+	go s.run(open)
+	return s
+}
+
+// run reads lines until EOF and closes the lines channel when done
+func (s *lineStream) run(open func() (io.ReadCloser, error)) {
+	defer close(s.lines)
+
+	r, err := open()
+	if err != nil {
+		s.err = err
+		return
+	}
+	defer r.Close()
+
+	// Compressed input is decompressed on the fly, e.g. cat app.log.gz | sift
+	decompressed, _, closeDecoder, err := decompress(r)
+	if err != nil {
+		s.err = err
+		return
+	}
+	defer closeDecoder()
+
+	// Lines are parsed in parallel, batching whatever has already arrived
+	// so a slow writer's lines are delivered as soon as they're complete
+	reader := newLineReader(decompressed, maxLineSize)
+	var readErr error
+	runParsePipeline(func() []rawLine {
+		if readErr != nil {
+			return nil
+		}
+		raw, err := reader.readRawLines(parseBatchSize, true)
+		readErr = err
+		return raw
+	}, 1, func(lines []LogLine) {
+		for _, line := range lines {
+			s.lines <- line
+		}
+	})
+
+	if readErr != io.EOF {
+		s.err = readErr
+	}
+}
+
+// waitForStreamLines returns a command that blocks until at least one line
+// is available, then returns it together with any other lines already buffered
+func waitForStreamLines(s *lineStream) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-s.lines
+		if !ok {
+			return streamEndedMsg{err: s.err}
+		}
+
+		batch := []LogLine{line}
+		for len(batch) < streamBatchSize {
+			select {
+			case line, ok := <-s.lines:
+				if !ok {
+					// The next wait will report the end of the stream
+					return newLinesMsg(batch)
+				}
+				batch = append(batch, line)
+			default:
+				return newLinesMsg(batch)
+			}
+		}
+
+		return newLinesMsg(batch)
+	}
+}
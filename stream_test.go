@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// collectStream drains a stream through waitForStreamLines until it ends
+func collectStream(t *testing.T, s *lineStream) ([]LogLine, error) {
+	t.Helper()
+
+	var lines []LogLine
+	for i := 0; i < 100; i++ {
+		switch msg := waitForStreamLines(s)().(type) {
+		case newLinesMsg:
+			lines = append(lines, msg...)
+		case streamEndedMsg:
+			return lines, msg.err
+		default:
+			t.Fatalf("Unexpected message type %T", msg)
+		}
+	}
+	t.Fatal("Stream did not end")
+	return nil, nil
+}
+
+// TestReaderStream tests reading lines from a stream source
+func TestReaderStream(t *testing.T) {
+	input := "{\"level\": \"info\"}\nnot json\n{\"level\": \"error\"}\n"
+	s := startReaderStream(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(input)), nil
+	})
+
+	lines, err := collectStream(t, s)
+	if err != nil {
+		t.Fatalf("Unexpected stream error: %v", err)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line.LineNumber != i+1 {
+			t.Errorf("Expected line number %d, got %d", i+1, line.LineNumber)
+		}
+	}
+	if !lines[0].IsValid || lines[1].IsValid || !lines[2].IsValid {
+		t.Error("JSON validity not detected correctly for streamed lines")
+	}
+}
+
+// TestReaderStreamOpenError tests that open failures end the stream with an error
+func TestReaderStreamOpenError(t *testing.T) {
+	openErr := errors.New("boom")
+	s := startReaderStream(func() (io.ReadCloser, error) {
+		return nil, openErr
+	})
+
+	lines, err := collectStream(t, s)
+	if len(lines) != 0 {
+		t.Errorf("Expected no lines, got %d", len(lines))
+	}
+	if !errors.Is(err, openErr) {
+		t.Errorf("Expected open error, got %v", err)
+	}
+}
+
+// TestIsStreamSource tests detection of stdin and named pipes
+func TestIsStreamSource(t *testing.T) {
+	if !isStreamSource("-") {
+		t.Error("'-' should be treated as a stream source")
+	}
+
+	regular := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(regular, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isStreamSource(regular) {
+		t.Error("Regular files should not be treated as stream sources")
+	}
+
+	if isStreamSource("nonexistent.log") {
+		t.Error("Missing files should not be treated as stream sources")
+	}
+}
+
+// TestStreamDisplayName tests the status bar name for stream sources
+func TestStreamDisplayName(t *testing.T) {
+	if name := streamDisplayName("-"); name != stdinDisplayName {
+		t.Errorf("Expected %q, got %q", stdinDisplayName, name)
+	}
+	if name := streamDisplayName("/tmp/app.fifo"); name != "/tmp/app.fifo" {
+		t.Errorf("Expected pipe path, got %q", name)
+	}
+}
+
+// TestStreamModelUpdate tests how the model consumes stream messages
+func TestStreamModelUpdate(t *testing.T) {
+	s := startReaderStream(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("line 1\n")), nil
+	})
+	model := Model{
+		filename:          stdinDisplayName,
+		stream:            s,
+		isFileFullyLoaded: true,
+		height:            10,
+		width:             80,
+	}
+
+	if cmd := model.Init(); cmd == nil {
+		t.Fatal("Init should return a stream command")
+	}
+
+	newModel, cmd := model.Update(newLinesMsg{{LineNumber: 1, RawLine: "line 1"}})
+	updatedModel := newModel.(Model)
+	if len(updatedModel.lines) != 1 {
+		t.Errorf("Expected 1 line after newLinesMsg, got %d", len(updatedModel.lines))
+	}
+	if cmd == nil {
+		t.Error("newLinesMsg should keep waiting on the stream")
+	}
+
+	newModel, cmd = updatedModel.Update(streamEndedMsg{})
+	updatedModel = newModel.(Model)
+	if !updatedModel.streamEnded {
+		t.Error("streamEndedMsg should mark the stream as ended")
+	}
+	if cmd != nil {
+		t.Error("No command expected after the stream ends")
+	}
+	if !strings.Contains(updatedModel.View(), "(EOF)") {
+		t.Error("Status bar should show that the stream ended")
+	}
+}
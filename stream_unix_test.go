@@ -0,0 +1,20 @@
+//go:build unix
+
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestIsStreamSourceNamedPipe tests that named pipes are streamed
+func TestIsStreamSourceNamedPipe(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "test.fifo")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("Named pipes not supported: %v", err)
+	}
+	if !isStreamSource(fifo) {
+		t.Error("Named pipes should be treated as stream sources")
+	}
+}
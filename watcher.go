@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultPollInterval is how often files are checked when polling
+const defaultPollInterval = 200 * time.Millisecond
+
+// Message sent when a followed file may have changed
+type fileEventMsg struct{}
+
+// fileWatcher notifies when followed files may have changed
+type fileWatcher interface {
+	// Events delivers a value whenever a followed file may have changed.
+	// Changes that happen before the last one is received are coalesced.
+	Events() <-chan struct{}
+	// Close stops watching
+	Close() error
+}
+
+// newFileWatcher watches filenames for changes with the native watcher,
+// falling back to polling every pollInterval when it's unavailable or forcePoll is set
+func newFileWatcher(filenames []string, pollInterval time.Duration, forcePoll bool) fileWatcher {
+	if !forcePoll {
+		if w, err := newNativeWatcher(filenames); err == nil {
+			return w
+		}
+	}
+	return newPollWatcher(pollInterval)
+}
+
+// waitForFileEvent returns a command that blocks until a followed file may
+// have changed, or until timeout has passed when it's positive
+func waitForFileEvent(w fileWatcher, timeout time.Duration) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		var expired <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		select {
+		case _, ok := <-w.Events():
+			if !ok {
+				return nil // Watcher was closed
+			}
+		case <-expired:
+		}
+		return fileEventMsg{}
+	}
+}
+
+// signalChange queues a change notification unless one is already pending
+func signalChange(events chan struct{}) {
+	select {
+	case events <- struct{}{}:
+	default:
+	}
+}
+
+// pollWatcher reports a possible change on every tick of a timer
+type pollWatcher struct {
+	events    chan struct{}
+	ticker    *time.Ticker
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newPollWatcher creates a watcher that signals every interval
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	w := &pollWatcher{
+		events: make(chan struct{}, 1),
+		ticker: time.NewTicker(interval),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run signals a change on every tick until the watcher is closed
+func (w *pollWatcher) run() {
+	defer close(w.events)
+
+	for {
+		select {
+		case <-w.ticker.C:
+			signalChange(w.events)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Events delivers a value on every tick
+func (w *pollWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Close stops the timer
+func (w *pollWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		w.ticker.Stop()
+		close(w.done)
+	})
+	return nil
+}
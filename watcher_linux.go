@@ -0,0 +1,126 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the directory events that may affect a followed file:
+// writes and truncation, plus the renames, deletes and creates of rotation
+const inotifyMask = unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE |
+	unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotifyWatcher watches the directories containing the followed files.
+// Watching the directory rather than the file keeps working when the file is
+// replaced during log rotation.
+type inotifyWatcher struct {
+	file      *os.File
+	events    chan struct{}
+	names     map[int]map[string]bool // Followed file names in each watched directory
+	closeOnce sync.Once
+}
+
+// newNativeWatcher creates an inotify watcher for filenames
+func newNativeWatcher(filenames []string) (fileWatcher, error) {
+	// A non-blocking descriptor goes through the runtime poller, so Close
+	// interrupts a pending read
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan struct{}, 1),
+		names:  make(map[int]map[string]bool),
+	}
+
+	for _, filename := range filenames {
+		paths := []string{filename}
+		// Writes to a symlinked log show up in the directory of its target
+		if target, err := filepath.EvalSymlinks(filename); err == nil && target != filename {
+			paths = append(paths, target)
+		}
+
+		for _, path := range paths {
+			dir, name := filepath.Split(path)
+			if dir == "" {
+				dir = "."
+			}
+			wd, err := unix.InotifyAddWatch(fd, dir, inotifyMask)
+			if err != nil {
+				w.file.Close()
+				return nil, err
+			}
+			if w.names[wd] == nil {
+				w.names[wd] = make(map[string]bool)
+			}
+			w.names[wd][name] = true
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// run reads inotify events until the watcher is closed, signalling a change
+// for every event about a followed file
+func (w *inotifyWatcher) run() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		changed := false
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			if nameEnd > n {
+				break
+			}
+			name := string(bytes.TrimRight(buf[nameStart:nameEnd], "\x00"))
+			offset = nameEnd
+
+			switch {
+			case event.Mask&unix.IN_Q_OVERFLOW != 0:
+				changed = true // Events were dropped, check anyway
+			case event.Mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0:
+				changed = true // The directory itself went away
+			case w.names[int(event.Wd)][name]:
+				changed = true
+			}
+		}
+
+		if changed {
+			signalChange(w.events)
+		}
+	}
+}
+
+// Events delivers a value whenever a followed file was written, truncated,
+// created, moved or deleted
+func (w *inotifyWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Close stops watching and releases the inotify descriptor
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		err = w.file.Close()
+	})
+	return err
+}
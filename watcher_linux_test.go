@@ -0,0 +1,80 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// expectEvent waits for a change notification from w
+func expectEvent(t *testing.T, w fileWatcher, what string) {
+	t.Helper()
+	select {
+	case <-w.Events():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected an event after %s", what)
+	}
+}
+
+// expectNoEvent checks that w doesn't report a change
+func expectNoEvent(t *testing.T, w fileWatcher, what string) {
+	t.Helper()
+	select {
+	case <-w.Events():
+		t.Fatalf("Expected no event after %s", what)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestInotifyWatcher tests that writes and rotation of a followed file are reported
+func TestInotifyWatcher(t *testing.T) {
+	path := writeTestFile(t, "app.log", []byte(`{"n": 1}`+"\n"))
+
+	w, err := newNativeWatcher([]string{path})
+	if err != nil {
+		t.Skipf("inotify unavailable: %v", err)
+	}
+	defer w.Close()
+
+	appendToFile(t, path, `{"n": 2}`+"\n")
+	expectEvent(t, w, "appending to the file")
+
+	// Other files in the same directory are ignored
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "other.log"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, w, "writing another file")
+
+	// Rotation moves the file away and creates a new one
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, w, "moving the file away")
+	if err := os.WriteFile(path, []byte(`{"n": 3}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, w, "recreating the file")
+}
+
+// TestInotifyWatcherClose tests that closing the watcher ends its events
+func TestInotifyWatcherClose(t *testing.T) {
+	path := writeTestFile(t, "app.log", nil)
+
+	w, err := newNativeWatcher([]string{path})
+	if err != nil {
+		t.Skipf("inotify unavailable: %v", err)
+	}
+	w.Close()
+
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Error("Expected the events channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close should stop the watcher")
+	}
+}
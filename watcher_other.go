@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// newNativeWatcher reports that there is no native watcher on this platform,
+// so files are polled
+func newNativeWatcher(filenames []string) (fileWatcher, error) {
+	return nil, errors.New("file events are not supported on this platform")
+}
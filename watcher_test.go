@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPollWatcher tests that the poll watcher signals on every interval
+func TestPollWatcher(t *testing.T) {
+	w := newPollWatcher(time.Millisecond)
+
+	select {
+	case <-w.Events():
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event after the poll interval")
+	}
+
+	w.Close()
+	w.Close() // Closing twice is harmless
+
+	// The events channel is closed once the watcher stops
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-w.Events():
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Events channel should be closed after Close")
+		}
+	}
+}
+
+// TestNewFileWatcherForcePoll tests that -poll bypasses the native watcher
+func TestNewFileWatcherForcePoll(t *testing.T) {
+	path := writeTestFile(t, "app.log", nil)
+	w := newFileWatcher([]string{path}, time.Second, true)
+	defer w.Close()
+
+	if _, ok := w.(*pollWatcher); !ok {
+		t.Errorf("Expected a poll watcher, got %T", w)
+	}
+}
+
+// TestFollowMsgWaitsForNextEvent tests that the next change is only awaited
+// once a check has finished
+func TestFollowMsgWaitsForNextEvent(t *testing.T) {
+	w := newPollWatcher(time.Hour)
+	defer w.Close()
+
+	model := Model{watcher: w, height: 10, width: 80}
+	if _, cmd := model.Update(followMsg{states: []followState{{}}, firstLineNumber: 1}); cmd == nil {
+		t.Error("Expected to wait for the next file event after a check")
+	}
+}